@@ -1,8 +1,14 @@
 package jobs
 
 import (
+	"bytes"
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/yaml"
 )
 
@@ -20,6 +26,27 @@ func WithServiceAccountNamespace(namespace string) AuthOption {
 	}
 }
 
+// WithServiceAccountAnnotations annotate the service account, e.g. for IRSA or workload identity
+func WithServiceAccountAnnotations(annotations map[string]string) AuthOption {
+	return func(a *AuthBuilder) {
+		a.serviceAccountAnnotations = annotations
+	}
+}
+
+// WithServiceAccountImagePullSecrets attach image pull secrets to the service account, pods using it inherit them
+func WithServiceAccountImagePullSecrets(imagePullSecrets []corev1.LocalObjectReference) AuthOption {
+	return func(a *AuthBuilder) {
+		a.imagePullSecrets = imagePullSecrets
+	}
+}
+
+// WithAuthLabels add labels to the cluster role, role binding and service account
+func WithAuthLabels(labels map[string]string) AuthOption {
+	return func(a *AuthBuilder) {
+		a.labels = labels
+	}
+}
+
 func GetAuth(opts ...AuthOption) (*rbacv1.ClusterRole, *rbacv1.ClusterRoleBinding, *corev1.ServiceAccount, error) {
 	ab := &AuthBuilder{}
 	for _, opt := range opts {
@@ -28,8 +55,36 @@ func GetAuth(opts ...AuthOption) (*rbacv1.ClusterRole, *rbacv1.ClusterRoleBindin
 	return ab.build()
 }
 
+// RenderAuthYAML returns the collector ClusterRole, ClusterRoleBinding and ServiceAccount
+// as a multi-document YAML, e.g. to pre-install them with GitOps
+func RenderAuthYAML(opts ...AuthOption) ([]byte, error) {
+	cr, rb, sa, err := GetAuth(opts...)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for _, obj := range []runtime.Object{cr, rb, sa} {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return nil, fmt.Errorf("converting %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, err)
+		}
+		// zero creation timestamp is rendered as null
+		unstructured.RemoveNestedField(content, "metadata", "creationTimestamp")
+		doc, err := yaml.Marshal(content)
+		if err != nil {
+			return nil, fmt.Errorf("marshaling %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, err)
+		}
+		buf.WriteString("---\n")
+		buf.Write(doc)
+	}
+	return buf.Bytes(), nil
+}
+
 type AuthBuilder struct {
-	namespace string
+	namespace                 string
+	serviceAccountAnnotations map[string]string
+	imagePullSecrets          []corev1.LocalObjectReference
+	labels                    map[string]string
 }
 
 func (b *AuthBuilder) build() (*rbacv1.ClusterRole, *rbacv1.ClusterRoleBinding, *corev1.ServiceAccount, error) {
@@ -58,6 +113,23 @@ func (b *AuthBuilder) build() (*rbacv1.ClusterRole, *rbacv1.ClusterRoleBinding,
 	if len(b.namespace) > 0 {
 		sa.Namespace = b.namespace
 	}
+	for key, val := range b.serviceAccountAnnotations {
+		if sa.Annotations == nil {
+			sa.Annotations = make(map[string]string)
+		}
+		sa.Annotations[key] = val
+	}
+	if len(b.imagePullSecrets) > 0 {
+		sa.ImagePullSecrets = append(sa.ImagePullSecrets, b.imagePullSecrets...)
+	}
+	for _, meta := range []*metav1.ObjectMeta{&cr.ObjectMeta, &rb.ObjectMeta, &sa.ObjectMeta} {
+		for key, val := range b.labels {
+			if meta.Labels == nil {
+				meta.Labels = make(map[string]string)
+			}
+			meta.Labels[key] = val
+		}
+	}
 	return &cr, &rb, &sa, nil
 
 }
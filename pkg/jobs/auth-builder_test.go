@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestGetAuthServiceAccountAnnotations(t *testing.T) {
+	annotations := map[string]string{"eks.amazonaws.com/role-arn": "arn:aws:iam::123456789012:role/node-collector"}
+	_, rb, sa, err := GetAuth(
+		WithServiceAccountNamespace("trivy-temp"),
+		WithServiceAccountAnnotations(annotations),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "trivy-temp", sa.Namespace)
+	assert.Equal(t, "trivy-temp", rb.Subjects[0].Namespace)
+	assert.Equal(t, annotations, sa.Annotations)
+
+	_, _, sa, err = GetAuth()
+	assert.NoError(t, err)
+	assert.Empty(t, sa.Annotations)
+}
+
+func TestGetAuthServiceAccountImagePullSecrets(t *testing.T) {
+	imagePullSecrets := []corev1.LocalObjectReference{{Name: "registry-a"}, {Name: "registry-b"}}
+	_, _, sa, err := GetAuth(WithServiceAccountImagePullSecrets(imagePullSecrets))
+	assert.NoError(t, err)
+	assert.Equal(t, imagePullSecrets, sa.ImagePullSecrets)
+}
+
+func TestRenderAuthYAML(t *testing.T) {
+	got, err := RenderAuthYAML(
+		WithServiceAccountNamespace("trivy-system"),
+		WithServiceAccountAnnotations(map[string]string{"eks.amazonaws.com/role-arn": "arn:aws:iam::123456789012:role/node-collector"}),
+	)
+	assert.NoError(t, err)
+	want, err := os.ReadFile(filepath.Join("testdata", "auth.golden.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, string(want), string(got))
+}
@@ -1,14 +1,38 @@
 package jobs
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 
+	containerimage "github.com/google/go-containerregistry/pkg/name"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/yaml"
 )
 
+// supportedNodeArchs are the architectures go and kubernetes release binaries for
+var supportedNodeArchs = []string{"amd64", "arm64", "arm", "386", "ppc64le", "s390x"}
+
+// supportedOutputFormats are the node-collector --format values
+var supportedOutputFormats = []string{"json", "table"}
+
+// controlPlaneTolerations tolerate the taints kubeadm set on control-plane nodes
+var controlPlaneTolerations = []corev1.Toleration{
+	{Key: "node-role.kubernetes.io/control-plane", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+	{Key: "node-role.kubernetes.io/master", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+}
+
 type JobOption func(*JobBuilder)
 
 func WithTemplate(template string) JobOption {
@@ -57,6 +81,14 @@ func WithAffinity(affinity *corev1.Affinity) JobOption {
 	}
 }
 
+// WithPodAffinity set pod affinity (e.g. co-locate with an agent pod), it is merged in
+// the pod affinity and keeps node affinity set by WithAffinity
+func WithPodAffinity(podAffinity *corev1.PodAffinity) JobOption {
+	return func(j *JobBuilder) {
+		j.podAffinity = podAffinity
+	}
+}
+
 func WithTolerations(tolerations []corev1.Toleration) JobOption {
 	return func(j *JobBuilder) {
 		j.tolerations = tolerations
@@ -69,6 +101,14 @@ func WithPriorityClassName(priorityClassName string) JobOption {
 	}
 }
 
+// WithPriority set integer pod priority for clusters without PriorityClasses,
+// it is ignored when a priority class name is set as the class takes precedence
+func WithPriority(priority *int32) JobOption {
+	return func(j *JobBuilder) {
+		j.priority = priority
+	}
+}
+
 func WithNodeCollectorImageRef(imageRef string) JobOption {
 	return func(j *JobBuilder) {
 		j.imageRef = imageRef
@@ -104,6 +144,13 @@ func WithImagePullSecrets(imagePullSecrets []corev1.LocalObjectReference) JobOpt
 	}
 }
 
+// WithAdditionalImagePullSecret append an image pull secret, template and WithImagePullSecrets secrets are kept
+func WithAdditionalImagePullSecret(name string) JobOption {
+	return func(j *JobBuilder) {
+		j.additionalImagePullSecrets = append(j.additionalImagePullSecrets, corev1.LocalObjectReference{Name: name})
+	}
+}
+
 func WithResourceRequirements(rr *corev1.ResourceRequirements) JobOption {
 	return func(j *JobBuilder) {
 		j.resourceRequirements = rr
@@ -115,24 +162,241 @@ func WithJobTimeout(timeout time.Duration) JobOption {
 	}
 }
 
+// WithNoDeadline clear the job active deadline, even when WithJobTimeout or a patch set it,
+// so a debugging collector is not killed before it can be inspected
+func WithNoDeadline(noDeadline bool) JobOption {
+	return func(j *JobBuilder) {
+		j.noDeadline = noDeadline
+	}
+}
+
 func WithNodeConfiguration(nodeConfig bool) JobOption {
 	return func(j *JobBuilder) {
 		j.nodeConfig = nodeConfig
 	}
 }
 
+// WithUseNodeSelectorParam hard-pin the pod to the node with a kubernetes.io/hostname nodeSelector,
+// set again after strategic merge patches, so affinity preferences or a PreferNoSchedule taint
+// on the node (a soft scheduling preference) can't get the pod scheduled on another node
 func WithUseNodeSelectorParam(useNodeSelector bool) JobOption {
 	return func(j *JobBuilder) {
 		j.useNodeSelector = useNodeSelector
 	}
 }
 
+func WithHostNetwork(hostNetwork bool) JobOption {
+	return func(j *JobBuilder) {
+		j.hostNetwork = hostNetwork
+	}
+}
+
+// WithDNSPolicy set pod dns policy, when host network is enabled and no policy
+// is set it default to ClusterFirstWithHostNet
+func WithDNSPolicy(dnsPolicy corev1.DNSPolicy) JobOption {
+	return func(j *JobBuilder) {
+		j.dnsPolicy = dnsPolicy
+	}
+}
+
+func WithDNSConfig(dnsConfig *corev1.PodDNSConfig) JobOption {
+	return func(j *JobBuilder) {
+		j.dnsConfig = dnsConfig
+	}
+}
+
+// WithSuspend create the job in suspended state, it can be resumed later by the collector
+func WithSuspend(suspend bool) JobOption {
+	return func(j *JobBuilder) {
+		j.suspend = suspend
+	}
+}
+
+// WithNodeArch pin the job to nodes matching the cpu architecture (e.g. amd64, arm64)
+func WithNodeArch(arch string) JobOption {
+	return func(j *JobBuilder) {
+		j.nodeArch = arch
+	}
+}
+
+// WithResourceRequirementsForContainer set resource requirements of the named container only
+func WithResourceRequirementsForContainer(containerName string, rr corev1.ResourceRequirements) JobOption {
+	return func(j *JobBuilder) {
+		if j.containerResourceRequirements == nil {
+			j.containerResourceRequirements = make(map[string]corev1.ResourceRequirements)
+		}
+		j.containerResourceRequirements[containerName] = rr
+	}
+}
+
+// WithPodSpecMutator add a hook mutating the pod spec, mutators run in order after
+// all other options are applied and before the job is validated
+func WithPodSpecMutator(mutator func(*corev1.PodSpec)) JobOption {
+	return func(j *JobBuilder) {
+		j.podSpecMutators = append(j.podSpecMutators, mutator)
+	}
+}
+
+// WithJobMutator add a hook mutating the whole job, mutators run in order after
+// all other options and pod spec mutators are applied and before the job is validated
+func WithJobMutator(mutator func(*batchv1.Job)) JobOption {
+	return func(j *JobBuilder) {
+		j.jobMutators = append(j.jobMutators, mutator)
+	}
+}
+
+// WithTolerateAll add a catch-all toleration, the job is scheduled on tainted nodes
+func WithTolerateAll() JobOption {
+	return func(j *JobBuilder) {
+		j.tolerateAll = true
+	}
+}
+
+// WithNodeFlag set the flag passing the node name to the collector in node config mode, default --node,
+// e.g. --node-name for custom collectors
+func WithNodeFlag(flag string) JobOption {
+	return func(j *JobBuilder) {
+		j.nodeFlag = flag
+	}
+}
+
+// WithOutputFormat append the --format arg to the collector container (json or table), supported by
+// newer node-collectors only. the collector parse json output, table is meant for displaying the output as is
+func WithOutputFormat(format string) JobOption {
+	return func(j *JobBuilder) {
+		j.outputFormat = format
+	}
+}
+
+// WithTolerationSeconds set tolerationSeconds on the job NoExecute tolerations that don't set it,
+// so the pod outlive brief NoExecute taints (e.g. spot eviction) for that window only.
+// the API only allow tolerationSeconds with the NoExecute effect, other tolerations are kept as is
+func WithTolerationSeconds(seconds int64) JobOption {
+	return func(j *JobBuilder) {
+		j.tolerationSeconds = &seconds
+	}
+}
+
+// WithControlPlaneTolerations tolerate the control-plane and legacy master NoSchedule taints,
+// so the job can be scheduled on control-plane nodes
+func WithControlPlaneTolerations() JobOption {
+	return func(j *JobBuilder) {
+		j.controlPlaneTolerations = true
+	}
+}
+
+// WithHostname set the pod hostname, it default to the pod name
+func WithHostname(hostname string) JobOption {
+	return func(j *JobBuilder) {
+		j.hostname = hostname
+	}
+}
+
+// WithSubdomain set the pod subdomain, the pod fqdn is <hostname>.<subdomain>.<namespace>.svc
+// when a headless service of the same name exists
+func WithSubdomain(subdomain string) JobOption {
+	return func(j *JobBuilder) {
+		j.subdomain = subdomain
+	}
+}
+
+// WithPodOverhead set the pod overhead, the RuntimeClass admission reject the pod when it does not
+// match the overhead of the pod runtime class
+func WithPodOverhead(overhead corev1.ResourceList) JobOption {
+	return func(j *JobBuilder) {
+		j.overhead = overhead
+	}
+}
+
+func WithReadinessGates(readinessGates []corev1.PodReadinessGate) JobOption {
+	return func(j *JobBuilder) {
+		j.readinessGates = readinessGates
+	}
+}
+
+// WithEphemeralStorage set the collector container ephemeral-storage request and limit, it is merged
+// with the cpu/memory resource requirements, a zero quantity is not set
+func WithEphemeralStorage(request, limit resource.Quantity) JobOption {
+	return func(j *JobBuilder) {
+		j.ephemeralStorageRequest = request
+		j.ephemeralStorageLimit = limit
+	}
+}
+
+// WithJobStrategicMergePatch apply a strategic merge patch (JSON or YAML) to the job,
+// it is applied after all other options and before mutators
+func WithJobStrategicMergePatch(patch []byte) JobOption {
+	return func(j *JobBuilder) {
+		j.strategicMergePatch = patch
+	}
+}
+
+// WithJobSpecOverlay merge a partial job spec (YAML or JSON) over the template spec
+// with strategic merge, it is applied before WithJobStrategicMergePatch
+func WithJobSpecOverlay(overlay []byte) JobOption {
+	return func(j *JobBuilder) {
+		j.jobSpecOverlay = overlay
+	}
+}
+
+// WithRegistryMirror rewrite container images of registry from (e.g. ghcr.io) to the mirror
+// registry to (e.g. registry.internal), the image repository, tag and digest are kept. docker hub images,
+// with or without registry in the reference, are mirrored from docker.io
+func WithRegistryMirror(from, to string) JobOption {
+	return func(j *JobBuilder) {
+		if j.registryMirrors == nil {
+			j.registryMirrors = make(map[string]string)
+		}
+		j.registryMirrors[strings.TrimSuffix(from, "/")] = strings.TrimSuffix(to, "/")
+	}
+}
+
+// WithConfigSecret mount a secret read-only in the collector container at mountPath,
+// the volume gets a unique name so it does not collide with other volumes
+func WithConfigSecret(secretName string, mountPath string) JobOption {
+	return func(j *JobBuilder) {
+		j.configSecrets = append(j.configSecrets, configSecret{secretName: secretName, mountPath: mountPath})
+	}
+}
+
+type configSecret struct {
+	secretName string
+	mountPath  string
+}
+
+// WithTTLSecondsAfterFinished set the job ttl after it finished, the job and its pods are then garbage collected
+func WithTTLSecondsAfterFinished(ttl *int32) JobOption {
+	return func(j *JobBuilder) {
+		j.ttlSecondsAfterFinished = ttl
+	}
+}
+
+// WithSelectorLabels set a manual job pod selector, the labels are added to the pod template
+// so the selector always match the job pods
+func WithSelectorLabels(selectorLabels map[string]string) JobOption {
+	return func(j *JobBuilder) {
+		j.selectorLabels = selectorLabels
+	}
+}
+
+// WithContainerArgs append args to the collector container args
+func WithContainerArgs(args []string) JobOption {
+	return func(j *JobBuilder) {
+		j.containerArgs = append(j.containerArgs, args...)
+	}
+}
+
 func GetJob(opts ...JobOption) (*batchv1.Job, error) {
+	return NewJobBuilder(opts...).build()
+}
+
+// NewJobBuilder instansiate a job builder with options, e.g. to Validate them
+func NewJobBuilder(opts ...JobOption) *JobBuilder {
 	jb := &JobBuilder{}
 	for _, opt := range opts {
 		opt(jb)
 	}
-	return jb.build()
+	return jb
 }
 
 type JobBuilder struct {
@@ -147,8 +411,10 @@ type JobBuilder struct {
 	securityContext      *corev1.SecurityContext
 	annotations          map[string]string
 	affinity             *corev1.Affinity
+	podAffinity          *corev1.PodAffinity
 	tolerations          []corev1.Toleration
 	priorityClassName    string
+	priority             *int32
 	volumes              []corev1.Volume
 	volumeMounts         []corev1.VolumeMount
 	imagePullSecrets     []corev1.LocalObjectReference
@@ -156,16 +422,82 @@ type JobBuilder struct {
 	timeout              time.Duration
 	nodeConfig           bool
 	useNodeSelector      bool
+	hostNetwork          bool
+	dnsPolicy            corev1.DNSPolicy
+	dnsConfig            *corev1.PodDNSConfig
+	suspend              bool
+	nodeArch             string
+
+	containerResourceRequirements map[string]corev1.ResourceRequirements
+	podSpecMutators               []func(*corev1.PodSpec)
+	jobMutators                   []func(*batchv1.Job)
+	tolerateAll                   bool
+	strategicMergePatch           []byte
+	jobSpecOverlay                []byte
+	registryMirrors               map[string]string
+	configSecrets                 []configSecret
+	additionalImagePullSecrets    []corev1.LocalObjectReference
+	ttlSecondsAfterFinished       *int32
+	selectorLabels                map[string]string
+	containerArgs                 []string
+	controlPlaneTolerations       bool
+	readinessGates                []corev1.PodReadinessGate
+	overhead                      corev1.ResourceList
+	hostname                      string
+	noDeadline                    bool
+	tolerationSeconds             *int64
+	outputFormat                  string
+	nodeFlag                      string
+	subdomain                     string
+	ephemeralStorageRequest       resource.Quantity
+	ephemeralStorageLimit         resource.Quantity
+
+	// CronJob wrapper fields, see GetCronJob
+	concurrencyPolicy          batchv1.ConcurrencyPolicy
+	successfulJobsHistoryLimit *int32
+	failedJobsHistoryLimit     *int32
+	startingDeadlineSeconds    *int64
+}
+
+// Validate run the build checks (template, containers, volume mounts, selector, namespace...)
+// without returning the job, it returns all errors found
+func (b *JobBuilder) Validate() error {
+	var errs []error
+	if len(b.namespace) > 0 {
+		if msgs := validation.IsDNS1123Label(b.namespace); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("invalid namespace %q: %s", b.namespace, strings.Join(msgs, ", ")))
+		}
+	}
+	if len(b.hostname) > 0 {
+		if msgs := validation.IsDNS1123Label(b.hostname); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("invalid hostname %q: %s", b.hostname, strings.Join(msgs, ", ")))
+		}
+	}
+	if len(b.subdomain) > 0 {
+		if msgs := validation.IsDNS1123Label(b.subdomain); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("invalid subdomain %q: %s", b.subdomain, strings.Join(msgs, ", ")))
+		}
+	}
+	if _, err := b.build(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
 }
 
 func (b *JobBuilder) build() (*batchv1.Job, error) {
 	template := getTemplate(b.template)
+	if len(template) == 0 {
+		return nil, fmt.Errorf("job template %q not found", b.template)
+	}
 	var job batchv1.Job
 
 	err := yaml.Unmarshal([]byte(template), &job)
 	if err != nil {
 		return nil, err
 	}
+	if len(job.Spec.Template.Spec.Containers) == 0 {
+		return nil, fmt.Errorf("job template %q has no containers", b.template)
+	}
 	job.Namespace = b.namespace
 	if len(b.name) > 0 {
 		job.Name = b.name
@@ -174,13 +506,35 @@ func (b *JobBuilder) build() (*batchv1.Job, error) {
 		job.Spec.Template.Spec.Containers[0].Image = b.imageRef
 	}
 	if b.nodeConfig {
-		job.Spec.Template.Spec.Containers[0].Args = append(job.Spec.Template.Spec.Containers[0].Args, "--node", b.nodeName)
+		nodeFlag := "--node"
+		if len(b.nodeFlag) > 0 {
+			nodeFlag = b.nodeFlag
+		}
+		job.Spec.Template.Spec.Containers[0].Args = append(job.Spec.Template.Spec.Containers[0].Args, nodeFlag, b.nodeName)
+	}
+	if len(b.containerArgs) > 0 {
+		job.Spec.Template.Spec.Containers[0].Args = append(job.Spec.Template.Spec.Containers[0].Args, b.containerArgs...)
+	}
+	if len(b.outputFormat) > 0 {
+		if !slices.Contains(supportedOutputFormats, b.outputFormat) {
+			return nil, fmt.Errorf("unsupported output format %q, supported: %s", b.outputFormat, strings.Join(supportedOutputFormats, ", "))
+		}
+		job.Spec.Template.Spec.Containers[0].Args = append(job.Spec.Template.Spec.Containers[0].Args, "--format", b.outputFormat)
 	}
 	if b.useNodeSelector {
 		job.Spec.Template.Spec.NodeSelector = map[string]string{
 			corev1.LabelHostname: b.nodeName,
 		}
 	}
+	if len(b.nodeArch) > 0 {
+		if !slices.Contains(supportedNodeArchs, b.nodeArch) {
+			return nil, fmt.Errorf("unsupported node architecture %q, supported: %s", b.nodeArch, strings.Join(supportedNodeArchs, ", "))
+		}
+		if job.Spec.Template.Spec.NodeSelector == nil {
+			job.Spec.Template.Spec.NodeSelector = make(map[string]string)
+		}
+		job.Spec.Template.Spec.NodeSelector[corev1.LabelArchStable] = b.nodeArch
+	}
 	// append lables
 	for key, val := range b.labels {
 		if job.Labels == nil {
@@ -199,36 +553,344 @@ func (b *JobBuilder) build() (*batchv1.Job, error) {
 		job.Spec.Template.Spec.ServiceAccountName = b.serviceAccount
 	}
 	if b.affinity != nil {
-		job.Spec.Template.Spec.Affinity = b.affinity
+		job.Spec.Template.Spec.Affinity = b.affinity.DeepCopy()
+	}
+	if b.podAffinity != nil {
+		// copy so the affinity passed to WithAffinity is not modified
+		affinity := job.Spec.Template.Spec.Affinity.DeepCopy()
+		if affinity == nil {
+			affinity = &corev1.Affinity{}
+		}
+		affinity.PodAffinity = b.podAffinity.DeepCopy()
+		job.Spec.Template.Spec.Affinity = affinity
 	}
 	if len(b.tolerations) > 0 {
-		job.Spec.Template.Spec.Tolerations = b.tolerations
+		job.Spec.Template.Spec.Tolerations = deepCopyAll(b.tolerations)
+	}
+	if b.controlPlaneTolerations {
+		for _, toleration := range controlPlaneTolerations {
+			if !slices.Contains(job.Spec.Template.Spec.Tolerations, toleration) {
+				job.Spec.Template.Spec.Tolerations = append(job.Spec.Template.Spec.Tolerations, toleration)
+			}
+		}
+	}
+	if b.tolerateAll {
+		job.Spec.Template.Spec.Tolerations = append(job.Spec.Template.Spec.Tolerations, corev1.Toleration{
+			Operator: corev1.TolerationOpExists,
+		})
+	}
+	if b.tolerationSeconds != nil {
+		for i, toleration := range job.Spec.Template.Spec.Tolerations {
+			if toleration.Effect == corev1.TaintEffectNoExecute && toleration.TolerationSeconds == nil {
+				job.Spec.Template.Spec.Tolerations[i].TolerationSeconds = ptr.To(*b.tolerationSeconds)
+			}
+		}
 	}
 	if b.priorityClassName != "" {
 		job.Spec.Template.Spec.PriorityClassName = b.priorityClassName
+	} else if b.priority != nil {
+		job.Spec.Template.Spec.Priority = ptr.To(*b.priority)
 	}
 	if b.podSecurityContext != nil {
-		job.Spec.Template.Spec.SecurityContext = b.podSecurityContext
+		job.Spec.Template.Spec.SecurityContext = b.podSecurityContext.DeepCopy()
 	}
 	if b.timeout > 0 {
 		job.Spec.ActiveDeadlineSeconds = ptr.To[int64](int64(b.timeout.Seconds()))
 	}
 	if b.securityContext != nil {
-		job.Spec.Template.Spec.Containers[0].SecurityContext = b.securityContext
+		job.Spec.Template.Spec.Containers[0].SecurityContext = b.securityContext.DeepCopy()
 	}
 	if len(b.volumes) > 0 {
-		job.Spec.Template.Spec.Volumes = b.volumes
+		job.Spec.Template.Spec.Volumes = deepCopyAll(b.volumes)
 	}
 	if len(b.imagePullSecrets) > 0 {
-		job.Spec.Template.Spec.ImagePullSecrets = b.imagePullSecrets
+		job.Spec.Template.Spec.ImagePullSecrets = deepCopyAll(b.imagePullSecrets)
 	}
-	if b.resourceRequirements != nil {
-		for _, c := range job.Spec.Template.Spec.Containers {
-			c.Resources = *b.resourceRequirements
+	for _, secret := range b.additionalImagePullSecrets {
+		if !slices.Contains(job.Spec.Template.Spec.ImagePullSecrets, secret) {
+			job.Spec.Template.Spec.ImagePullSecrets = append(job.Spec.Template.Spec.ImagePullSecrets, secret)
 		}
 	}
+	applyResourceRequirements(&job.Spec.Template.Spec, b.resourceRequirements, b.containerResourceRequirements)
+	applyEphemeralStorage(&job.Spec.Template.Spec.Containers[0].Resources, b.ephemeralStorageRequest, b.ephemeralStorageLimit)
 	if len(b.volumeMounts) > 0 {
-		job.Spec.Template.Spec.Containers[0].VolumeMounts = b.volumeMounts
+		job.Spec.Template.Spec.Containers[0].VolumeMounts = deepCopyAll(b.volumeMounts)
+	}
+	for _, cs := range b.configSecrets {
+		addConfigSecret(&job.Spec.Template.Spec, cs)
+	}
+	if b.hostNetwork {
+		job.Spec.Template.Spec.HostNetwork = true
+		job.Spec.Template.Spec.DNSPolicy = corev1.DNSClusterFirstWithHostNet
+	}
+	if len(b.dnsPolicy) > 0 {
+		job.Spec.Template.Spec.DNSPolicy = b.dnsPolicy
+	}
+	if b.dnsConfig != nil {
+		job.Spec.Template.Spec.DNSConfig = b.dnsConfig.DeepCopy()
+	}
+	if b.suspend {
+		job.Spec.Suspend = ptr.To[bool](true)
+	}
+	if len(b.selectorLabels) > 0 {
+		if job.Spec.Template.Labels == nil {
+			job.Spec.Template.Labels = make(map[string]string)
+		}
+		for key, val := range b.selectorLabels {
+			job.Spec.Template.Labels[key] = val
+		}
+		job.Spec.Selector = &metav1.LabelSelector{MatchLabels: maps.Clone(b.selectorLabels)}
+		job.Spec.ManualSelector = ptr.To[bool](true)
+	}
+	if len(b.readinessGates) > 0 {
+		job.Spec.Template.Spec.ReadinessGates = deepCopyAll(b.readinessGates)
+	}
+	if len(b.hostname) > 0 {
+		job.Spec.Template.Spec.Hostname = b.hostname
+	}
+	if len(b.subdomain) > 0 {
+		job.Spec.Template.Spec.Subdomain = b.subdomain
+	}
+	if len(b.overhead) > 0 {
+		job.Spec.Template.Spec.Overhead = b.overhead.DeepCopy()
+	}
+	if b.ttlSecondsAfterFinished != nil {
+		job.Spec.TTLSecondsAfterFinished = ptr.To(*b.ttlSecondsAfterFinished)
+	}
+	if len(b.jobSpecOverlay) > 0 {
+		patch, err := jobSpecOverlayPatch(b.jobSpecOverlay)
+		if err != nil {
+			return nil, err
+		}
+		patchedJob, err := applyStrategicMergePatch(&job, patch)
+		if err != nil {
+			return nil, err
+		}
+		job = *patchedJob
+	}
+	if len(b.strategicMergePatch) > 0 {
+		patchedJob, err := applyStrategicMergePatch(&job, b.strategicMergePatch)
+		if err != nil {
+			return nil, err
+		}
+		job = *patchedJob
+	}
+	if b.noDeadline {
+		job.Spec.ActiveDeadlineSeconds = nil
+	}
+	if b.useNodeSelector {
+		pinNode(&job.Spec.Template.Spec, b.nodeName)
+	}
+	if len(b.registryMirrors) > 0 {
+		mirrorImages(&job.Spec.Template.Spec, b.registryMirrors)
+	}
+	// pod spec mutators run after all other options
+	for _, mutate := range b.podSpecMutators {
+		mutate(&job.Spec.Template.Spec)
+	}
+	// job mutators run last
+	for _, mutate := range b.jobMutators {
+		mutate(&job)
+	}
+	// mutators may remove containers
+	if len(job.Spec.Template.Spec.Containers) == 0 {
+		return nil, fmt.Errorf("job %q has no containers", job.Name)
+	}
+	if err := validateVolumeMounts(job.Spec.Template.Spec); err != nil {
+		return nil, err
+	}
+	if err := validateSelector(&job); err != nil {
+		return nil, err
 	}
 	return &job, nil
 }
+
+// pinNode set the hostname nodeSelector, keeping other selector labels (e.g. arch)
+func pinNode(podSpec *corev1.PodSpec, nodeName string) {
+	if podSpec.NodeSelector == nil {
+		podSpec.NodeSelector = make(map[string]string)
+	}
+	podSpec.NodeSelector[corev1.LabelHostname] = nodeName
+}
+
+// mirrorImages rewrite init and regular container images registry to their mirror
+func mirrorImages(podSpec *corev1.PodSpec, registryMirrors map[string]string) {
+	for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+		for i := range containers {
+			containers[i].Image = mirrorImage(containers[i].Image, registryMirrors)
+		}
+	}
+}
+
+// mirrorImage rewrite the image registry to its mirror, images without registry are docker hub images
+// matched by docker.io or index.docker.io
+func mirrorImage(image string, registryMirrors map[string]string) string {
+	ref, err := containerimage.ParseReference(image)
+	if err != nil {
+		return image
+	}
+	registry := ref.Context().RegistryStr()
+	mirror, ok := registryMirrors[registry]
+	if !ok && registry == containerimage.DefaultRegistry {
+		mirror, ok = registryMirrors["docker.io"]
+	}
+	if !ok {
+		return image
+	}
+	mirrored := mirror + "/" + ref.Context().RepositoryStr()
+	if _, ok := ref.(containerimage.Digest); ok {
+		return mirrored + "@" + ref.Identifier()
+	}
+	return mirrored + ":" + ref.Identifier()
+}
+
+// jobSpecOverlayPatch validate the overlay is a job spec and wrap it into a job patch,
+// the overlay itself is used rather than the decoded spec so zero values do not clear template fields
+func jobSpecOverlayPatch(overlay []byte) ([]byte, error) {
+	var spec batchv1.JobSpec
+	if err := yaml.UnmarshalStrict(overlay, &spec); err != nil {
+		return nil, fmt.Errorf("parsing job spec overlay: %w", err)
+	}
+	specJSON, err := yaml.YAMLToJSON(overlay)
+	if err != nil {
+		return nil, fmt.Errorf("parsing job spec overlay: %w", err)
+	}
+	return json.Marshal(map[string]json.RawMessage{"spec": specJSON})
+}
+
+func applyStrategicMergePatch(job *batchv1.Job, patch []byte) (*batchv1.Job, error) {
+	patchJSON, err := yaml.YAMLToJSON(patch)
+	if err != nil {
+		return nil, fmt.Errorf("parsing job strategic merge patch: %w", err)
+	}
+	jobJSON, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+	patchedJSON, err := strategicpatch.StrategicMergePatch(jobJSON, patchJSON, batchv1.Job{})
+	if err != nil {
+		return nil, fmt.Errorf("applying job strategic merge patch: %w", err)
+	}
+	var patchedJob batchv1.Job
+	if err = json.Unmarshal(patchedJSON, &patchedJob); err != nil {
+		return nil, fmt.Errorf("applying job strategic merge patch: %w", err)
+	}
+	return &patchedJob, nil
+}
+
+// addConfigSecret add a read-only secret volume and mount it in the first container
+func addConfigSecret(podSpec *corev1.PodSpec, cs configSecret) {
+	volumeName := uniqueVolumeName(podSpec, "config-secret")
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: volumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: cs.secretName,
+			},
+		},
+	})
+	podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+		Name:      volumeName,
+		MountPath: cs.mountPath,
+		ReadOnly:  true,
+	})
+}
+
+// uniqueVolumeName return prefix suffixed with the first index not used by a pod volume
+func uniqueVolumeName(podSpec *corev1.PodSpec, prefix string) string {
+	used := make(map[string]bool, len(podSpec.Volumes))
+	for _, v := range podSpec.Volumes {
+		used[v.Name] = true
+	}
+	for i := 0; ; i++ {
+		name := fmt.Sprintf("%s-%d", prefix, i)
+		if !used[name] {
+			return name
+		}
+	}
+}
+
+// applyResourceRequirements set resources on all containers, per container
+// resource requirements take precedence over the global ones
+func applyResourceRequirements(podSpec *corev1.PodSpec, rr *corev1.ResourceRequirements, containerRR map[string]corev1.ResourceRequirements) {
+	for i := range podSpec.Containers {
+		if r, ok := containerRR[podSpec.Containers[i].Name]; ok {
+			podSpec.Containers[i].Resources = *r.DeepCopy()
+			continue
+		}
+		if rr != nil {
+			podSpec.Containers[i].Resources = *rr.DeepCopy()
+		}
+	}
+}
+
+// deepCopyAll returns a deep copy of the items, so built jobs don't share memory with the options
+// (e.g. concurrent builds appending tolerations to the same backing array)
+func deepCopyAll[T any, PT interface {
+	*T
+	DeepCopyInto(*T)
+}](in []T) []T {
+	if in == nil {
+		return nil
+	}
+	out := make([]T, len(in))
+	for i := range in {
+		PT(&in[i]).DeepCopyInto(&out[i])
+	}
+	return out
+}
+
+// validateSelector check a manual job selector match the pod template labels,
+// the job controller can't find the job pods otherwise
+func validateSelector(job *batchv1.Job) error {
+	if job.Spec.Selector == nil {
+		return nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(job.Spec.Selector)
+	if err != nil {
+		return fmt.Errorf("invalid job selector: %w", err)
+	}
+	if !selector.Matches(labels.Set(job.Spec.Template.Labels)) {
+		return fmt.Errorf("job selector %q does not match pod template labels", selector.String())
+	}
+	return nil
+}
+
+// applyEphemeralStorage set ephemeral-storage on the resources, maps are copied as they may be shared with options
+func applyEphemeralStorage(resources *corev1.ResourceRequirements, request, limit resource.Quantity) {
+	if !request.IsZero() {
+		resources.Requests = maps.Clone(resources.Requests)
+		if resources.Requests == nil {
+			resources.Requests = make(corev1.ResourceList)
+		}
+		resources.Requests[corev1.ResourceEphemeralStorage] = request
+	}
+	if !limit.IsZero() {
+		resources.Limits = maps.Clone(resources.Limits)
+		if resources.Limits == nil {
+			resources.Limits = make(corev1.ResourceList)
+		}
+		resources.Limits[corev1.ResourceEphemeralStorage] = limit
+	}
+}
+
+// validateVolumeMounts check every container volume mount reference a declared pod volume
+func validateVolumeMounts(podSpec corev1.PodSpec) error {
+	volumes := make(map[string]bool, len(podSpec.Volumes))
+	for _, v := range podSpec.Volumes {
+		volumes[v.Name] = true
+	}
+	var danglingMounts []string
+	containers := append(append([]corev1.Container{}, podSpec.InitContainers...), podSpec.Containers...)
+	for _, c := range containers {
+		for _, vm := range c.VolumeMounts {
+			if !volumes[vm.Name] {
+				danglingMounts = append(danglingMounts, fmt.Sprintf("%s/%s", c.Name, vm.Name))
+			}
+		}
+	}
+	if len(danglingMounts) > 0 {
+		return fmt.Errorf("volume mounts reference undeclared volumes: %s", strings.Join(danglingMounts, ", "))
+	}
+	return nil
+}
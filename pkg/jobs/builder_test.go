@@ -3,6 +3,7 @@ package jobs
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	batchv1 "k8s.io/api/batch/v1"
@@ -163,3 +164,659 @@ func TestLoadBuilder(t *testing.T) {
 		})
 	}
 }
+
+func TestBuilderDNS(t *testing.T) {
+	dnsConfig := &corev1.PodDNSConfig{
+		Nameservers: []string{"10.0.0.10"},
+		Searches:    []string{"registry.internal"},
+	}
+	tests := []struct {
+		name            string
+		opts            []JobOption
+		wantPolicy      corev1.DNSPolicy
+		wantConfig      *corev1.PodDNSConfig
+		wantHostNetwork bool
+	}{
+		{
+			name:       "template default",
+			wantPolicy: corev1.DNSClusterFirst,
+		},
+		{
+			name:       "custom dns policy and config",
+			opts:       []JobOption{WithDNSPolicy(corev1.DNSNone), WithDNSConfig(dnsConfig)},
+			wantPolicy: corev1.DNSNone,
+			wantConfig: dnsConfig,
+		},
+		{
+			name:            "host network default dns policy",
+			opts:            []JobOption{WithHostNetwork(true)},
+			wantPolicy:      corev1.DNSClusterFirstWithHostNet,
+			wantHostNetwork: true,
+		},
+		{
+			name:            "host network with explicit dns policy",
+			opts:            []JobOption{WithHostNetwork(true), WithDNSPolicy(corev1.DNSDefault)},
+			wantPolicy:      corev1.DNSDefault,
+			wantHostNetwork: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotJob, err := GetJob(append([]JobOption{WithTemplate(NodeCollectorName)}, tt.opts...)...)
+			assert.NoError(t, err)
+			podSpec := gotJob.Spec.Template.Spec
+			assert.Equal(t, tt.wantPolicy, podSpec.DNSPolicy)
+			assert.Equal(t, tt.wantConfig, podSpec.DNSConfig)
+			assert.Equal(t, tt.wantHostNetwork, podSpec.HostNetwork)
+		})
+	}
+}
+
+func TestBuilderSuspend(t *testing.T) {
+	gotJob, err := GetJob(WithTemplate(NodeCollectorName))
+	assert.NoError(t, err)
+	assert.Nil(t, gotJob.Spec.Suspend)
+
+	gotJob, err = GetJob(WithTemplate(NodeCollectorName), WithSuspend(true))
+	assert.NoError(t, err)
+	assert.Equal(t, ptr.To[bool](true), gotJob.Spec.Suspend)
+}
+
+func TestBuilderNodeArch(t *testing.T) {
+	tests := []struct {
+		name             string
+		opts             []JobOption
+		wantNodeSelector map[string]string
+		wantErr          bool
+	}{
+		{
+			name:             "arch selector",
+			opts:             []JobOption{WithNodeArch("arm64")},
+			wantNodeSelector: map[string]string{corev1.LabelArchStable: "arm64"},
+		},
+		{
+			name: "arch selector alongside hostname selector",
+			opts: []JobOption{WithNodeArch("amd64"), WithNodeName("node-1"), WithUseNodeSelectorParam(true)},
+			wantNodeSelector: map[string]string{
+				corev1.LabelArchStable: "amd64",
+				corev1.LabelHostname:   "node-1",
+			},
+		},
+		{
+			name:    "unsupported arch",
+			opts:    []JobOption{WithNodeArch("mips")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotJob, err := GetJob(append([]JobOption{WithTemplate(NodeCollectorName)}, tt.opts...)...)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantNodeSelector, gotJob.Spec.Template.Spec.NodeSelector)
+		})
+	}
+}
+
+func TestJobBuilderValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []JobOption
+		wantErrs []string
+	}{
+		{
+			name: "valid options",
+			opts: []JobOption{WithTemplate(NodeCollectorName), WithNamespace("trivy-temp")},
+		},
+		{
+			name:     "unknown template",
+			opts:     []JobOption{WithTemplate("unknown")},
+			wantErrs: []string{`job template "unknown" not found`},
+		},
+		{
+			name: "invalid namespace and dangling mount",
+			opts: []JobOption{
+				WithTemplate(NodeCollectorName),
+				WithNamespace("Trivy_Temp"),
+				WithContainerVolumeMounts([]corev1.VolumeMount{{Name: "extra", MountPath: "/extra"}}),
+			},
+			wantErrs: []string{`invalid namespace "Trivy_Temp"`, "node-collector/extra"},
+		},
+		{
+			name: "selector not matching labels",
+			opts: []JobOption{
+				WithTemplate(NodeCollectorName),
+				WithJobMutator(func(job *batchv1.Job) {
+					job.Spec.Selector = &v1.LabelSelector{MatchLabels: map[string]string{"app": "other"}}
+				}),
+			},
+			wantErrs: []string{"does not match pod template labels"},
+		},
+		{
+			name: "no containers",
+			opts: []JobOption{
+				WithTemplate(NodeCollectorName),
+				WithPodSpecMutator(func(podSpec *corev1.PodSpec) {
+					podSpec.Containers = nil
+				}),
+			},
+			wantErrs: []string{"no containers"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewJobBuilder(tt.opts...).Validate()
+			if len(tt.wantErrs) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			for _, wantErr := range tt.wantErrs {
+				assert.ErrorContains(t, err, wantErr)
+			}
+		})
+	}
+}
+
+func TestBuilderVolumeMountsValidation(t *testing.T) {
+	volumes := []corev1.Volume{{Name: "var-lib-kubelet"}}
+	tests := []struct {
+		name    string
+		opts    []JobOption
+		wantErr string
+	}{
+		{
+			name: "mounts match volumes",
+			opts: []JobOption{
+				WithPodVolumes(volumes),
+				WithContainerVolumeMounts([]corev1.VolumeMount{{Name: "var-lib-kubelet", MountPath: "/var/lib/kubelet"}}),
+			},
+		},
+		{
+			name: "dangling mounts",
+			opts: []JobOption{
+				WithPodVolumes(volumes),
+				WithContainerVolumeMounts([]corev1.VolumeMount{
+					{Name: "var-lib-kubelet", MountPath: "/var/lib/kubelet"},
+					{Name: "etc-kubernetes", MountPath: "/etc/kubernetes"},
+					{Name: "etc-systemd", MountPath: "/etc/systemd"},
+				}),
+			},
+			wantErr: "volume mounts reference undeclared volumes: node-collector/etc-kubernetes, node-collector/etc-systemd",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := GetJob(append([]JobOption{WithTemplate(NodeCollectorName)}, tt.opts...)...)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestBuilderResourceRequirements(t *testing.T) {
+	collectorRR := corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+	}
+	globalRR := corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("200m")},
+	}
+	sidecarRR := corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10m")},
+	}
+
+	gotJob, err := GetJob(WithTemplate(NodeCollectorName), WithResourceRequirements(&globalRR))
+	assert.NoError(t, err)
+	assert.Equal(t, globalRR, gotJob.Spec.Template.Spec.Containers[0].Resources)
+
+	gotJob, err = GetJob(WithTemplate(NodeCollectorName),
+		WithResourceRequirements(&globalRR),
+		WithResourceRequirementsForContainer(NodeCollectorName, collectorRR))
+	assert.NoError(t, err)
+	assert.Equal(t, collectorRR, gotJob.Spec.Template.Spec.Containers[0].Resources)
+
+	podSpec := corev1.PodSpec{Containers: []corev1.Container{{Name: NodeCollectorName}, {Name: "sidecar"}}}
+	applyResourceRequirements(&podSpec, nil, map[string]corev1.ResourceRequirements{
+		NodeCollectorName: collectorRR,
+		"sidecar":         sidecarRR,
+	})
+	assert.Equal(t, collectorRR, podSpec.Containers[0].Resources)
+	assert.Equal(t, sidecarRR, podSpec.Containers[1].Resources)
+}
+
+func TestBuilderPodSpecMutator(t *testing.T) {
+	gotJob, err := GetJob(
+		WithTemplate(NodeCollectorName),
+		WithPriorityClassName("high"),
+		WithPodSpecMutator(func(spec *corev1.PodSpec) {
+			spec.ShareProcessNamespace = ptr.To[bool](true)
+			spec.PriorityClassName = "low"
+		}),
+		WithPodSpecMutator(func(spec *corev1.PodSpec) {
+			spec.SchedulerName = "custom-" + spec.PriorityClassName
+		}),
+	)
+	assert.NoError(t, err)
+	podSpec := gotJob.Spec.Template.Spec
+	assert.Equal(t, ptr.To[bool](true), podSpec.ShareProcessNamespace)
+	// mutators run after options and in order
+	assert.Equal(t, "low", podSpec.PriorityClassName)
+	assert.Equal(t, "custom-low", podSpec.SchedulerName)
+}
+
+func TestBuilderJobMutator(t *testing.T) {
+	gotJob, err := GetJob(
+		WithTemplate(NodeCollectorName),
+		WithLabels(map[string]string{"app": "trivy"}),
+		WithPodSpecMutator(func(spec *corev1.PodSpec) {
+			spec.SchedulerName = "custom"
+		}),
+		WithJobMutator(func(job *batchv1.Job) {
+			job.Spec.Completions = ptr.To[int32](2)
+			job.Labels["app"] = "custom-" + job.Spec.Template.Spec.SchedulerName
+		}),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, ptr.To[int32](2), gotJob.Spec.Completions)
+	// job mutators run after options and pod spec mutators
+	assert.Equal(t, "custom-custom", gotJob.Labels["app"])
+}
+
+func TestBuilderTolerateAll(t *testing.T) {
+	gpuToleration := corev1.Toleration{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}
+	gotJob, err := GetJob(
+		WithTemplate(NodeCollectorName),
+		WithTolerations([]corev1.Toleration{gpuToleration}),
+		WithTolerateAll(),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []corev1.Toleration{
+		gpuToleration,
+		{Operator: corev1.TolerationOpExists},
+	}, gotJob.Spec.Template.Spec.Tolerations)
+}
+
+func TestBuilderStrategicMergePatch(t *testing.T) {
+	patch := []byte(`
+spec:
+  backoffLimit: 3
+  template:
+    spec:
+      containers:
+        - name: node-collector
+          env:
+            - name: HTTPS_PROXY
+              value: http://proxy.internal:3128
+`)
+	gotJob, err := GetJob(WithTemplate(NodeCollectorName), WithJobStrategicMergePatch(patch))
+	assert.NoError(t, err)
+	assert.Equal(t, ptr.To[int32](3), gotJob.Spec.BackoffLimit)
+	container := gotJob.Spec.Template.Spec.Containers[0]
+	// containers are merged by name, keeping template fields
+	assert.Equal(t, "ghcr.io/aquasecurity/node-collector:0.1.1", container.Image)
+	assert.Equal(t, []corev1.EnvVar{{Name: "HTTPS_PROXY", Value: "http://proxy.internal:3128"}}, container.Env)
+	assert.Len(t, container.VolumeMounts, 8)
+
+	_, err = GetJob(WithTemplate(NodeCollectorName), WithJobStrategicMergePatch([]byte(`{"spec":`)))
+	assert.ErrorContains(t, err, "job strategic merge patch")
+}
+
+func TestBuilderJobSpecOverlay(t *testing.T) {
+	overlay := []byte(`
+backoffLimit: 2
+template:
+  metadata:
+    labels:
+      team: security
+`)
+	gotJob, err := GetJob(WithTemplate(NodeCollectorName), WithJobSpecOverlay(overlay))
+	assert.NoError(t, err)
+	assert.Equal(t, ptr.To[int32](2), gotJob.Spec.BackoffLimit)
+	assert.Equal(t, "security", gotJob.Spec.Template.Labels["team"])
+	// template fields are kept
+	assert.Equal(t, "node-collector", gotJob.Spec.Template.Labels["app"])
+	assert.Equal(t, "ghcr.io/aquasecurity/node-collector:0.1.1", gotJob.Spec.Template.Spec.Containers[0].Image)
+	assert.Len(t, gotJob.Spec.Template.Spec.Volumes, 8)
+
+	_, err = GetJob(WithTemplate(NodeCollectorName), WithJobSpecOverlay([]byte(`backoffLimit: [`)))
+	assert.ErrorContains(t, err, "parsing job spec overlay")
+	_, err = GetJob(WithTemplate(NodeCollectorName), WithJobSpecOverlay([]byte(`backoffLimits: 2`)))
+	assert.ErrorContains(t, err, "parsing job spec overlay")
+}
+
+func TestBuilderRegistryMirror(t *testing.T) {
+	tests := []struct {
+		name      string
+		imageRef  string
+		wantImage string
+	}{
+		{
+			name:      "tag",
+			imageRef:  "ghcr.io/aquasecurity/node-collector:0.1.1",
+			wantImage: "registry.internal/aquasecurity/node-collector:0.1.1",
+		},
+		{
+			name:      "digest",
+			imageRef:  "ghcr.io/aquasecurity/node-collector@sha256:0000000000000000000000000000000000000000000000000000000000000000",
+			wantImage: "registry.internal/aquasecurity/node-collector@sha256:0000000000000000000000000000000000000000000000000000000000000000",
+		},
+		{
+			name:      "other registry",
+			imageRef:  "quay.io/aquasec/node-collector:0.1.1",
+			wantImage: "quay.io/aquasec/node-collector:0.1.1",
+		},
+		{
+			name:      "docker hub",
+			imageRef:  "aquasec/node-collector:0.1.1",
+			wantImage: "registry.internal/dockerhub/aquasec/node-collector:0.1.1",
+		},
+		{
+			name:      "docker hub official image",
+			imageRef:  "docker.io/alpine:3.19",
+			wantImage: "registry.internal/dockerhub/library/alpine:3.19",
+		},
+		{
+			name:      "docker hub organization is not a registry",
+			imageRef:  "aquasecurity/node-collector:0.1.1",
+			wantImage: "registry.internal/dockerhub/aquasecurity/node-collector:0.1.1",
+		},
+		{
+			name:      "registry with port",
+			imageRef:  "localhost:5000/node-collector:0.1.1",
+			wantImage: "localhost:5000/node-collector:0.1.1",
+		},
+		{
+			name:      "registry prefix only",
+			imageRef:  "ghcr.io.example.com/aquasecurity/node-collector:0.1.1",
+			wantImage: "ghcr.io.example.com/aquasecurity/node-collector:0.1.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotJob, err := GetJob(
+				WithTemplate(NodeCollectorName),
+				WithNodeCollectorImageRef(tt.imageRef),
+				WithRegistryMirror("ghcr.io", "registry.internal/"),
+				WithRegistryMirror("docker.io", "registry.internal/dockerhub"),
+				// a docker hub organization
+				WithRegistryMirror("aquasecurity", "registry.internal/aquasecurity"),
+			)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantImage, gotJob.Spec.Template.Spec.Containers[0].Image)
+		})
+	}
+}
+
+func TestBuilderConfigSecret(t *testing.T) {
+	gotJob, err := GetJob(
+		WithTemplate(NodeCollectorName),
+		WithConfigSecret("registry-creds", "/etc/registry"),
+		WithConfigSecret("api-token", "/etc/api"),
+	)
+	assert.NoError(t, err)
+	podSpec := gotJob.Spec.Template.Spec
+	volumes := podSpec.Volumes
+	assert.Equal(t, []corev1.Volume{
+		{Name: "config-secret-0", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "registry-creds"}}},
+		{Name: "config-secret-1", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "api-token"}}},
+	}, volumes[len(volumes)-2:])
+	mounts := podSpec.Containers[0].VolumeMounts
+	assert.Equal(t, []corev1.VolumeMount{
+		{Name: "config-secret-0", MountPath: "/etc/registry", ReadOnly: true},
+		{Name: "config-secret-1", MountPath: "/etc/api", ReadOnly: true},
+	}, mounts[len(mounts)-2:])
+
+	// volume name must not collide with existing volumes
+	podSpec = corev1.PodSpec{Volumes: []corev1.Volume{{Name: "config-secret-0"}, {Name: "config-secret-2"}}}
+	assert.Equal(t, "config-secret-1", uniqueVolumeName(&podSpec, "config-secret"))
+}
+
+func TestBuilderPriority(t *testing.T) {
+	tests := []struct {
+		name                  string
+		priorityClassName     string
+		priority              *int32
+		wantPriorityClassName string
+		wantPriority          *int32
+	}{
+		{name: "priority", priority: ptr.To[int32](1000), wantPriority: ptr.To[int32](1000)},
+		{name: "priority class name takes precedence", priorityClassName: "high", priority: ptr.To[int32](1000), wantPriorityClassName: "high"},
+		{name: "no priority"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotJob, err := GetJob(
+				WithTemplate(NodeCollectorName),
+				WithPriorityClassName(tt.priorityClassName),
+				WithPriority(tt.priority),
+			)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantPriorityClassName, gotJob.Spec.Template.Spec.PriorityClassName)
+			assert.Equal(t, tt.wantPriority, gotJob.Spec.Template.Spec.Priority)
+		})
+	}
+}
+
+func TestBuilderAdditionalImagePullSecret(t *testing.T) {
+	gotJob, err := GetJob(
+		WithTemplate(NodeCollectorName),
+		WithImagePullSecrets([]corev1.LocalObjectReference{{Name: "base"}}),
+		WithAdditionalImagePullSecret("registry-a"),
+		WithAdditionalImagePullSecret("registry-b"),
+		WithAdditionalImagePullSecret("base"),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []corev1.LocalObjectReference{
+		{Name: "base"},
+		{Name: "registry-a"},
+		{Name: "registry-b"},
+	}, gotJob.Spec.Template.Spec.ImagePullSecrets)
+}
+
+func TestBuilderPodAffinity(t *testing.T) {
+	nodeAffinity := &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+			NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+				MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "kubernetes.io/os", Operator: corev1.NodeSelectorOpIn, Values: []string{"linux"}}},
+			}},
+		},
+	}
+	podAffinity := &corev1.PodAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+			LabelSelector: &v1.LabelSelector{MatchLabels: map[string]string{"app": "monitoring-agent"}},
+			TopologyKey:   corev1.LabelHostname,
+		}},
+	}
+	affinity := &corev1.Affinity{NodeAffinity: nodeAffinity}
+	gotJob, err := GetJob(
+		WithTemplate(NodeCollectorName),
+		WithAffinity(affinity),
+		WithPodAffinity(podAffinity),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, &corev1.Affinity{NodeAffinity: nodeAffinity, PodAffinity: podAffinity}, gotJob.Spec.Template.Spec.Affinity)
+	// affinity option is not modified
+	assert.Nil(t, affinity.PodAffinity)
+
+	gotJob, err = GetJob(WithTemplate(NodeCollectorName), WithPodAffinity(podAffinity))
+	assert.NoError(t, err)
+	assert.Equal(t, &corev1.Affinity{PodAffinity: podAffinity}, gotJob.Spec.Template.Spec.Affinity)
+}
+
+func TestBuilderSelectorLabels(t *testing.T) {
+	selectorLabels := map[string]string{"org.example/app": "node-collector", "org.example/team": "security"}
+	gotJob, err := GetJob(WithTemplate(NodeCollectorName), WithSelectorLabels(selectorLabels))
+	assert.NoError(t, err)
+	assert.Equal(t, &v1.LabelSelector{MatchLabels: selectorLabels}, gotJob.Spec.Selector)
+	assert.Equal(t, ptr.To[bool](true), gotJob.Spec.ManualSelector)
+	// template labels are kept and selector labels are added
+	assert.Equal(t, map[string]string{
+		"app":              "node-collector",
+		"org.example/app":  "node-collector",
+		"org.example/team": "security",
+	}, gotJob.Spec.Template.Labels)
+
+	_, err = GetJob(
+		WithTemplate(NodeCollectorName),
+		WithSelectorLabels(selectorLabels),
+		WithJobMutator(func(job *batchv1.Job) {
+			delete(job.Spec.Template.Labels, "org.example/team")
+		}),
+	)
+	assert.ErrorContains(t, err, "does not match pod template labels")
+}
+
+func TestBuilderControlPlaneTolerations(t *testing.T) {
+	controlPlaneToleration := corev1.Toleration{Key: "node-role.kubernetes.io/control-plane", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}
+	gpuToleration := corev1.Toleration{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}
+	gotJob, err := GetJob(
+		WithTemplate(NodeCollectorName),
+		WithTolerations([]corev1.Toleration{gpuToleration, controlPlaneToleration}),
+		WithControlPlaneTolerations(),
+	)
+	assert.NoError(t, err)
+	// tolerations already set are not duplicated
+	assert.Equal(t, []corev1.Toleration{
+		gpuToleration,
+		controlPlaneToleration,
+		{Key: "node-role.kubernetes.io/master", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+	}, gotJob.Spec.Template.Spec.Tolerations)
+}
+
+func TestBuilderReadinessGates(t *testing.T) {
+	readinessGates := []corev1.PodReadinessGate{{ConditionType: "example.com/collector-ready"}}
+	gotJob, err := GetJob(WithTemplate(NodeCollectorName), WithReadinessGates(readinessGates))
+	assert.NoError(t, err)
+	assert.Equal(t, readinessGates, gotJob.Spec.Template.Spec.ReadinessGates)
+}
+
+func TestBuilderOutputFormat(t *testing.T) {
+	gotJob, err := GetJob(
+		WithTemplate(NodeCollectorName),
+		WithNodeConfiguration(true),
+		WithNodeName("node-1"),
+		WithOutputFormat("json"),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"k8s", "--node", "node-1", "--format", "json"}, gotJob.Spec.Template.Spec.Containers[0].Args)
+
+	_, err = GetJob(WithTemplate(NodeCollectorName), WithOutputFormat("yaml"))
+	assert.ErrorContains(t, err, `unsupported output format "yaml", supported: json, table`)
+}
+
+func TestBuilderNodeFlag(t *testing.T) {
+	gotJob, err := GetJob(
+		WithTemplate(NodeCollectorName),
+		WithNodeConfiguration(true),
+		WithNodeName("node-1"),
+		WithNodeFlag("--node-name"),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"k8s", "--node-name", "node-1"}, gotJob.Spec.Template.Spec.Containers[0].Args)
+}
+
+func TestBuilderTolerationSeconds(t *testing.T) {
+	tolerations := []corev1.Toleration{
+		{Key: "spot", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+		{Key: "gpu", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+		{Key: "maintenance", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute, TolerationSeconds: ptr.To[int64](600)},
+	}
+	gotJob, err := GetJob(WithTemplate(NodeCollectorName), WithTolerations(tolerations), WithTolerationSeconds(30))
+	assert.NoError(t, err)
+	assert.Equal(t, []corev1.Toleration{
+		{Key: "spot", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute, TolerationSeconds: ptr.To[int64](30)},
+		// tolerationSeconds is only valid with NoExecute
+		{Key: "gpu", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+		// explicit tolerationSeconds is kept
+		{Key: "maintenance", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute, TolerationSeconds: ptr.To[int64](600)},
+	}, gotJob.Spec.Template.Spec.Tolerations)
+	// tolerations option is not modified
+	assert.Nil(t, tolerations[0].TolerationSeconds)
+}
+
+func TestBuilderNoDeadline(t *testing.T) {
+	gotJob, err := GetJob(WithTemplate(NodeCollectorName), WithJobTimeout(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, ptr.To[int64](60), gotJob.Spec.ActiveDeadlineSeconds)
+
+	gotJob, err = GetJob(WithTemplate(NodeCollectorName), WithJobTimeout(time.Minute), WithNoDeadline(true))
+	assert.NoError(t, err)
+	assert.Nil(t, gotJob.Spec.ActiveDeadlineSeconds)
+
+	// deadline set by a patch is cleared too
+	gotJob, err = GetJob(
+		WithTemplate(NodeCollectorName),
+		WithJobStrategicMergePatch([]byte(`{"spec":{"activeDeadlineSeconds":300}}`)),
+		WithNoDeadline(true),
+	)
+	assert.NoError(t, err)
+	assert.Nil(t, gotJob.Spec.ActiveDeadlineSeconds)
+}
+
+func TestBuilderHostnameSubdomain(t *testing.T) {
+	gotJob, err := GetJob(WithTemplate(NodeCollectorName))
+	assert.NoError(t, err)
+	assert.Empty(t, gotJob.Spec.Template.Spec.Hostname)
+	assert.Empty(t, gotJob.Spec.Template.Spec.Subdomain)
+
+	gotJob, err = GetJob(WithTemplate(NodeCollectorName), WithHostname("collector-node-1"), WithSubdomain("node-collectors"))
+	assert.NoError(t, err)
+	assert.Equal(t, "collector-node-1", gotJob.Spec.Template.Spec.Hostname)
+	assert.Equal(t, "node-collectors", gotJob.Spec.Template.Spec.Subdomain)
+
+	err = NewJobBuilder(WithTemplate(NodeCollectorName), WithHostname("Collector_1"), WithSubdomain("node.collectors")).Validate()
+	assert.ErrorContains(t, err, `invalid hostname "Collector_1"`)
+	assert.ErrorContains(t, err, `invalid subdomain "node.collectors"`)
+}
+
+func TestBuilderPodOverhead(t *testing.T) {
+	gotJob, err := GetJob(WithTemplate(NodeCollectorName))
+	assert.NoError(t, err)
+	assert.Nil(t, gotJob.Spec.Template.Spec.Overhead)
+
+	overhead := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("250m"),
+		corev1.ResourceMemory: resource.MustParse("120Mi"),
+	}
+	gotJob, err = GetJob(WithTemplate(NodeCollectorName), WithPodOverhead(overhead))
+	assert.NoError(t, err)
+	assert.Equal(t, overhead, gotJob.Spec.Template.Spec.Overhead)
+}
+
+func TestBuilderEphemeralStorage(t *testing.T) {
+	rr := &corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+		Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+	}
+	gotJob, err := GetJob(
+		WithTemplate(NodeCollectorName),
+		WithResourceRequirements(rr),
+		WithEphemeralStorage(resource.MustParse("1Gi"), resource.MustParse("2Gi")),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:              resource.MustParse("100m"),
+			corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceMemory:           resource.MustParse("128Mi"),
+			corev1.ResourceEphemeralStorage: resource.MustParse("2Gi"),
+		},
+	}, gotJob.Spec.Template.Spec.Containers[0].Resources)
+	// resource requirements option is not modified
+	assert.NotContains(t, rr.Requests, corev1.ResourceEphemeralStorage)
+
+	// zero limit is not set
+	gotJob, err = GetJob(WithTemplate(NodeCollectorName), WithEphemeralStorage(resource.MustParse("1Gi"), resource.Quantity{}))
+	assert.NoError(t, err)
+	resources := gotJob.Spec.Template.Spec.Containers[0].Resources
+	assert.Equal(t, resource.MustParse("1Gi"), resources.Requests[corev1.ResourceEphemeralStorage])
+	assert.NotContains(t, resources.Limits, corev1.ResourceEphemeralStorage)
+	assert.Equal(t, resource.MustParse("50M"), resources.Requests[corev1.ResourceMemory])
+}
@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"sync"
+	"time"
+)
+
+type resultCacheKey struct {
+	nodeName string
+	imageRef string
+}
+
+type cachedResult struct {
+	result    Result
+	expiresAt time.Time
+}
+
+// resultCache keep collector results in memory, keyed by node name and collector image ref
+type resultCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[resultCacheKey]cachedResult
+	// collected keep the keys collected at least once, expired entries included
+	collected map[resultCacheKey]bool
+}
+
+func newResultCache(ttl time.Duration) *resultCache {
+	return &resultCache{
+		ttl:       ttl,
+		entries:   make(map[resultCacheKey]cachedResult),
+		collected: make(map[resultCacheKey]bool),
+	}
+}
+
+// get return a copy of the cached result if it is still fresh at now
+func (c *resultCache) get(key resultCacheKey, now time.Time) (*Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !now.Before(cached.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	result := cached.result
+	return &result, true
+}
+
+func (c *resultCache) set(key resultCacheKey, result *Result, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cachedResult{result: *result, expiresAt: now.Add(c.ttl)}
+	c.collected[key] = true
+}
+
+// markCollected record key as collected without caching a result
+func (c *resultCache) markCollected(key resultCacheKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.collected[key] = true
+}
+
+// hasCollected returns true when a result was cached for key, even if it expired since
+func (c *resultCache) hasCollected(key resultCacheKey) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.collected[key]
+}
@@ -0,0 +1,20 @@
+package jobs
+
+import "time"
+
+// Clock is the interface wrapping time functions used by the timeout logic,
+// it allows deterministic timeout in tests
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -0,0 +1,83 @@
+package jobs
+
+import (
+	"time"
+
+	"github.com/aquasecurity/trivy-kubernetes/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// CollectorBuilder is a fluent alternative to collector options, it ultimately
+// produces the same Collector as NewCollector with equivalent options
+type CollectorBuilder struct {
+	cluster k8s.Cluster
+	opts    []CollectorOption
+}
+
+// NewCollectorBuilder instansiate new collector builder
+func NewCollectorBuilder(cluster k8s.Cluster) *CollectorBuilder {
+	return &CollectorBuilder{cluster: cluster}
+}
+
+func (b *CollectorBuilder) WithNamespace(namespace string) *CollectorBuilder {
+	return b.WithOptions(WithJobNamespace(namespace))
+}
+
+func (b *CollectorBuilder) WithImage(imageRef string) *CollectorBuilder {
+	return b.WithOptions(WithImageRef(imageRef))
+}
+
+func (b *CollectorBuilder) WithTemplateName(name string) *CollectorBuilder {
+	return b.WithOptions(WithJobTemplateName(name))
+}
+
+func (b *CollectorBuilder) WithName(name string) *CollectorBuilder {
+	return b.WithOptions(WithName(name))
+}
+
+func (b *CollectorBuilder) WithLabels(labels map[string]string) *CollectorBuilder {
+	return b.WithOptions(WithJobLabels(labels))
+}
+
+func (b *CollectorBuilder) WithAnnotations(annotations map[string]string) *CollectorBuilder {
+	return b.WithOptions(WithJobAnnotation(annotations))
+}
+
+func (b *CollectorBuilder) WithTimeout(timeout time.Duration) *CollectorBuilder {
+	return b.WithOptions(WithTimetout(timeout))
+}
+
+func (b *CollectorBuilder) WithCollectorTimeout(timeout time.Duration) *CollectorBuilder {
+	return b.WithOptions(WithCollectorTimeout(timeout))
+}
+
+func (b *CollectorBuilder) WithServiceAccount(sa string) *CollectorBuilder {
+	return b.WithOptions(WithServiceAccount(sa))
+}
+
+func (b *CollectorBuilder) WithAffinity(affinity *corev1.Affinity) *CollectorBuilder {
+	return b.WithOptions(WithJobAffinity(affinity))
+}
+
+func (b *CollectorBuilder) WithTolerations(tolerations []corev1.Toleration) *CollectorBuilder {
+	return b.WithOptions(WithJobTolerations(tolerations))
+}
+
+func (b *CollectorBuilder) WithNodeConfig(nodeConfig bool) *CollectorBuilder {
+	return b.WithOptions(WithNodeConfig(nodeConfig))
+}
+
+func (b *CollectorBuilder) WithUseNodeSelector(useNodeSelector bool) *CollectorBuilder {
+	return b.WithOptions(WithUseNodeSelector(useNodeSelector))
+}
+
+// WithOptions append collector options, for settings without a dedicated builder method
+func (b *CollectorBuilder) WithOptions(opts ...CollectorOption) *CollectorBuilder {
+	b.opts = append(b.opts, opts...)
+	return b
+}
+
+// Build returns the collector
+func (b *CollectorBuilder) Build() Collector {
+	return NewCollector(b.cluster, b.opts...)
+}
@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aquasecurity/trivy-kubernetes/pkg/k8s"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+type testCluster struct {
+	k8s.Cluster
+	clientset  *kubernetes.Clientset
+	restConfig *rest.Config
+}
+
+func (c *testCluster) GetK8sClientSet() *kubernetes.Clientset {
+	return c.clientset
+}
+
+func (c *testCluster) GetRestConfig() *rest.Config {
+	return c.restConfig
+}
+
+func TestCollectorBuilder(t *testing.T) {
+	cluster := &testCluster{clientset: kubernetes.NewForConfigOrDie(&rest.Config{Host: "https://localhost"})}
+	tolerations := []corev1.Toleration{{Operator: corev1.TolerationOpExists}}
+
+	got := NewCollectorBuilder(cluster).
+		WithNamespace("trivy-temp").
+		WithImage("ghcr.io/aquasecurity/node-collector:0.1.1").
+		WithTemplateName(NodeCollectorName).
+		WithLabels(map[string]string{TrivyCollectorName: NodeCollectorName}).
+		WithTimeout(5 * time.Minute).
+		WithTolerations(tolerations).
+		WithNodeConfig(true).
+		WithOptions(WithMaxLogBytes(1024)).
+		Build()
+
+	want := NewCollector(cluster,
+		WithJobNamespace("trivy-temp"),
+		WithImageRef("ghcr.io/aquasecurity/node-collector:0.1.1"),
+		WithJobTemplateName(NodeCollectorName),
+		WithJobLabels(map[string]string{TrivyCollectorName: NodeCollectorName}),
+		WithTimetout(5*time.Minute),
+		WithJobTolerations(tolerations),
+		WithNodeConfig(true),
+		WithMaxLogBytes(1024),
+	)
+	assert.Equal(t, want, got)
+}
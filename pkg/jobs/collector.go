@@ -1,37 +1,123 @@
 package jobs
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	"github.com/aquasecurity/trivy-kubernetes/pkg/k8s"
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"golang.org/x/time/rate"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	k8sapierror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
 )
 
 const (
 	NodeCollectorName = "node-collector"
 
+	tracerName = "github.com/aquasecurity/trivy-kubernetes/pkg/jobs"
+
+	defaultNamespaceReadyTimeout = time.Minute
+	defaultNamespacePollInterval = time.Second
+
+	defaultSchedulingPollInterval = time.Second
+
 	// job headers
 	TrivyCollectorName = "trivy.collector.name"
 	TrivyAutoCreated   = "trivy.automatic.created"
 	TrivyResourceName  = "trivy.resource.name"
 	TrivyResourceKind  = "trivy.resource.kind"
+
+	// PodManagementFinalizer keep collector pods until their output is read, see WithPodManagementFinalizer
+	PodManagementFinalizer = "trivy.aquasecurity.github.io/node-collector"
 )
 
+// ErrMaxLogBytesExceeded is returned when collector output exceed the max log bytes cap
+var ErrMaxLogBytesExceeded = errors.New("collector output exceeded max log bytes")
+
+// ErrLogReadTimeout is returned when reading collector logs exceed the log read timeout
+var ErrLogReadTimeout = errors.New("reading logs timed out")
+
+// ErrNodeUnschedulable is returned when applying a job to a cordoned node
+var ErrNodeUnschedulable = errors.New("node is unschedulable")
+
+// ErrNodeRemoved is returned when the node is deleted while its collector job runs
+var ErrNodeRemoved = errors.New("node was removed")
+
+// ErrSchedulingTimeout is returned when no collector pod is running within the scheduling timeout
+var ErrSchedulingTimeout = errors.New("collector pod was not scheduled in time")
+
+// ErrLogsUnavailable is returned when a completed job has empty logs and its pod is gone (e.g. garbage collected),
+// the output is unknown rather than empty
+var ErrLogsUnavailable = errors.New("collector logs are unavailable")
+
+// ErrNamespaceNotFound is returned when the collector namespace must exist but is missing, see WithRequireExistingNamespace
+var ErrNamespaceNotFound = errors.New("namespace not found")
+
+// ErrCollectorClosed is returned when the collector is used after Close
+var ErrCollectorClosed = errors.New("collector is closed")
+
+// Collector is safe for concurrent use (e.g. controller workers), each call work on a snapshot of the config
 type Collector interface {
 	ApplyAndCollect(ctx context.Context, nodeName string) (string, error)
+	ApplyAndCollectResult(ctx context.Context, nodeName string) (*Result, error)
+	ApplyAndCollectInNamespace(ctx context.Context, nodeName string, namespace string) (string, error)
 	Apply(ctx context.Context, nodeName string) (*batchv1.Job, error)
 	AppendLabels(opts ...CollectorOption)
 	Cleanup(ctx context.Context)
+	CleanupAndWaitNamespace(ctx context.Context, timeout time.Duration) error
+	ListCollectorJobs(ctx context.Context) ([]CollectorJobInfo, error)
+	CleanupJobs(ctx context.Context, selector labels.Selector) error
+	CleanupPods(ctx context.Context, job *batchv1.Job) error
+	ResumeJob(ctx context.Context, job *batchv1.Job) error
+	GetJobEvents(ctx context.Context, job *batchv1.Job) ([]corev1.Event, error)
+	ReadResultFile(ctx context.Context, job *batchv1.Job) ([]byte, error)
+	ExecInCollector(ctx context.Context, job *batchv1.Job, container string, cmd []string) (string, string, error)
+	ValidateJob(ctx context.Context, job *batchv1.Job) error
+	EstimateFootprint(nodeNames []string) corev1.ResourceList
+	Healthy(ctx context.Context) error
+	DiffJob(ctx context.Context, nodeName string) (bool, string, error)
+	CollectKubeletConfig(ctx context.Context, nodeName string) (*KubeletConfig, error)
+	GetJobStatus(ctx context.Context, job *batchv1.Job) (JobStatus, error)
+	CollectStream(ctx context.Context, nodeNames []string, concurrency int) <-chan NodeResult
+	CollectDecoder(ctx context.Context, nodeName string) (*json.Decoder, func() error, error)
+	Close() error
 }
 
 type jobCollector struct {
-	cluster k8s.Cluster
+	cluster   k8s.Cluster
+	clientset kubernetes.Interface
+	// mu guard collector config against concurrent AppendLabels, methods work on a config snapshot
+	mu *sync.RWMutex
+	// lifecycle is cancelled by Close to abort in-flight collections (job watches, log streams)
+	lifecycle *collectorLifecycle
 	// timeout duration for collection job to complete it task before is cancelled default 0
 	timeout              time.Duration
 	logsReader           LogsReader
@@ -40,12 +126,14 @@ type jobCollector struct {
 	templateName         string
 	namespace            string
 	priorityClassName    string
+	priority             *int32
 	name                 string
 	serviceAccount       string
 	podSecurityContext   *corev1.PodSecurityContext
 	securityContext      *corev1.SecurityContext
 	imageRef             string
 	affinity             *corev1.Affinity
+	podAffinity          *corev1.PodAffinity
 	tolerations          []corev1.Toleration
 	volumes              []corev1.Volume
 	volumeMounts         []corev1.VolumeMount
@@ -54,6 +142,75 @@ type jobCollector struct {
 	resourceRequirements *corev1.ResourceRequirements
 	nodeConfig           bool
 	useNodeSelector      bool
+	hostNetwork          bool
+	dnsPolicy            corev1.DNSPolicy
+	dnsConfig            *corev1.PodDNSConfig
+	maxLogBytes          int64
+	logReadTimeout       time.Duration
+	deadlineFromContext  bool
+	rateLimiter          *rate.Limiter
+	suspend              bool
+	checkUnschedulable   bool
+	nodeArch             string
+
+	containerResourceRequirements  map[string]corev1.ResourceRequirements
+	tracerProvider                 trace.TracerProvider
+	clock                          Clock
+	podExecutor                    PodExecutor
+	resultFilePath                 string
+	podSpecMutators                []func(*corev1.PodSpec)
+	jobMutators                    []func(*batchv1.Job)
+	outputValidator                func([]byte) error
+	resultPostProcessors           []func([]byte) ([]byte, error)
+	tolerateAll                    bool
+	strategicMergePatch            []byte
+	jobSpecOverlay                 []byte
+	registryMirrors                map[string]string
+	stdinConfig                    []byte
+	pullCredentials                func(ctx context.Context) ([]byte, error)
+	failFastOnForbidden            bool
+	startupArgs                    []string
+	nodeLabelsToCopy               []string
+	nodeAnnotationsToCopy          []string
+	contextAnnotations             []contextAnnotation
+	configSecrets                  []configSecret
+	additionalImagePullSecrets     []string
+	adaptiveTimeout                func(node corev1.Node) time.Duration
+	serviceAccountAnnotations      map[string]string
+	serviceAccountImagePullSecrets []corev1.LocalObjectReference
+	ownNamespace                   bool
+	requireExistingNamespace       bool
+	namespaceReadyTimeout          time.Duration
+	namespacePollInterval          time.Duration
+	schedulingTimeout              time.Duration
+	schedulingPollInterval         time.Duration
+	ttlSecondsAfterFinished        *int32
+	podManagementFinalizer         bool
+	selectorLabels                 map[string]string
+	argsTemplate                   string
+	controlPlaneTolerations        bool
+	readinessGates                 []corev1.PodReadinessGate
+	ephemeralStorageRequest        resource.Quantity
+	ephemeralStorageLimit          resource.Quantity
+	nodeLister                     corev1listers.NodeLister
+	beforeCleanup                  func(ctx context.Context, job *batchv1.Job)
+	drainTimeout                   time.Duration
+	onJobFailed                    func(job *batchv1.Job, reason string)
+	evictionRetries                int
+	nonZeroExitResults             bool
+	noDeadline                     bool
+	tolerationSeconds              *int64
+	outputFormat                   string
+	nodeFlag                       string
+	commonLabels                   map[string]string
+	// unmanagedAuth use pre-installed rbac, see WithManagedAuth
+	unmanagedAuth     bool
+	deletePropagation metav1.DeletionPropagation
+	deleteGracePeriod *int64
+	versionCheck      bool
+	resultCache       *resultCache
+	// optionErr is the error of invalid options (e.g. an unknown profile), returned by collections and Apply
+	optionErr error
 }
 
 type CollectorOption func(*jobCollector)
@@ -87,12 +244,26 @@ func WithJobNamespace(namespace string) CollectorOption {
 	}
 }
 
+// WithPodPriority set integer priority on collector pods, WithPodPriorityClassName takes precedence if both are set
+func WithPodPriority(priority *int32) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.priority = priority
+	}
+}
+
 func WithPodPriorityClassName(priorityClassName string) CollectorOption {
 	return func(jc *jobCollector) {
 		jc.priorityClassName = priorityClassName
 	}
 }
 
+// WithJobPodAffinity set collector pods affinity to other pods, see WithPodAffinity
+func WithJobPodAffinity(podAffinity *corev1.PodAffinity) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.podAffinity = podAffinity
+	}
+}
+
 func WithJobAffinity(affinity *corev1.Affinity) CollectorOption {
 	return func(jc *jobCollector) {
 		jc.affinity = affinity
@@ -176,190 +347,1925 @@ func WithCollectorTimeout(timeout time.Duration) CollectorOption {
 	}
 }
 
+// WithUseNodeSelector hard-pin Apply jobs to the node, see WithUseNodeSelectorParam,
+// ApplyAndCollect jobs are always pinned
 func WithUseNodeSelector(useNodeSelector bool) CollectorOption {
 	return func(jc *jobCollector) {
 		jc.useNodeSelector = useNodeSelector
 	}
 }
 
-func NewCollector(
-	cluster k8s.Cluster,
-	opts ...CollectorOption,
-) Collector {
-	jc := &jobCollector{
-		cluster:    cluster,
-		timeout:    0,
-		logsReader: NewLogsReader(cluster.GetK8sClientSet()),
+func WithPodHostNetwork(hostNetwork bool) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.hostNetwork = hostNetwork
 	}
-	for _, opt := range opts {
-		opt(jc)
+}
+
+func WithPodDNSPolicy(dnsPolicy corev1.DNSPolicy) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.dnsPolicy = dnsPolicy
 	}
-	return jc
 }
 
-// AppendLabels Append labels to job
-func (jb *jobCollector) AppendLabels(opts ...CollectorOption) {
-	for _, opt := range opts {
-		opt(jb)
+func WithPodDNSConfig(dnsConfig *corev1.PodDNSConfig) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.dnsConfig = dnsConfig
 	}
 }
 
-type ObjectRef struct {
-	Kind      string
-	Name      string
-	Namespace string
+// WithMaxLogBytes cap the collector output read from logs, output exceeding
+// the cap is truncated and ErrMaxLogBytesExceeded is returned
+func WithMaxLogBytes(n int64) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.maxLogBytes = n
+	}
 }
 
-// ApplyAndCollect deploy k8s job by template to  specific node  and namespace, it read pod logs
-// cleaning up job and returning it output (for cli use-case)
-func (jb *jobCollector) ApplyAndCollect(ctx context.Context, nodeName string) (string, error) {
+// WithLogReadTimeout set timeout for reading collector logs once the job completed
+func WithLogReadTimeout(timeout time.Duration) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.logReadTimeout = timeout
+	}
+}
 
-	_, err := jb.getTrivyNamespace(ctx)
-	if err != nil {
-		if k8sapierror.IsNotFound(err) {
-			trivyNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: jb.namespace}}
-			_, err = jb.cluster.GetK8sClientSet().CoreV1().Namespaces().Create(ctx, trivyNamespace, metav1.CreateOptions{})
-			if err != nil {
-				return "", err
-			}
-		}
+// WithDeadlineFromContext derive job active deadline from the remaining time of the context deadline,
+// the collector timeout is used when it is shorter than the remaining time
+func WithDeadlineFromContext(deadlineFromContext bool) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.deadlineFromContext = deadlineFromContext
 	}
-	if jb.nodeConfig {
-		cr, rb, sa, err := GetAuth(WithServiceAccountNamespace(jb.namespace))
-		if err != nil {
-			return "", fmt.Errorf("running node-collector job: %w", err)
-		}
-		_, err = jb.cluster.GetK8sClientSet().RbacV1().ClusterRoles().Create(ctx, cr, metav1.CreateOptions{})
-		if err != nil {
-			return "", fmt.Errorf("creating cluster role: %w", err)
-		}
-		_, err = jb.cluster.GetK8sClientSet().CoreV1().ServiceAccounts(jb.namespace).Create(ctx, sa, metav1.CreateOptions{})
-		if err != nil {
-			return "", fmt.Errorf("creating service account: %w", err)
+}
+
+// WithQPSLimit rate limit API mutating calls (create/delete) issued by the collector, reads and dry-runs
+// are not limited. a collector shared across nodes collection smooth the load on the api server
+func WithQPSLimit(qps float64, burst int) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.rateLimiter = rate.NewLimiter(rate.Limit(qps), burst)
+	}
+}
+
+func WithJobSuspend(suspend bool) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.suspend = suspend
+	}
+}
+
+// WithCheckUnschedulableNode check the node is schedulable before applying a job,
+// cordoned nodes are rejected with ErrNodeUnschedulable so callers can skip them
+func WithCheckUnschedulableNode(checkUnschedulable bool) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.checkUnschedulable = checkUnschedulable
+	}
+}
+
+func WithJobNodeArch(arch string) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.nodeArch = arch
+	}
+}
+
+// WithContainerResourceRequirementsForContainer set resource requirements of the named container only
+func WithContainerResourceRequirementsForContainer(containerName string, rr corev1.ResourceRequirements) CollectorOption {
+	return func(jc *jobCollector) {
+		if jc.containerResourceRequirements == nil {
+			jc.containerResourceRequirements = make(map[string]corev1.ResourceRequirements)
 		}
-		_, err = jb.cluster.GetK8sClientSet().RbacV1().ClusterRoleBindings().Create(ctx, rb, metav1.CreateOptions{})
-		if err != nil {
-			return "", fmt.Errorf("creating role binding: %w", err)
+		jc.containerResourceRequirements[containerName] = rr
+	}
+}
+
+// WithTracerProvider set the tracer provider used to trace collection phases, default to no-op
+func WithTracerProvider(tp trace.TracerProvider) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.tracerProvider = tp
+	}
+}
+
+// WithCollectorClock set the clock used by the collector timeout logic
+func WithCollectorClock(clock Clock) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.clock = clock
+	}
+}
+
+// WithPodExecutor set the executor used to run commands in collector pods,
+// default to the SPDY executor of the cluster rest config
+func WithPodExecutor(podExecutor PodExecutor) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.podExecutor = podExecutor
+	}
+}
+
+// WithResultFromFile read collector results from a file in the collector container
+// rather than from its logs, the container must still be running to be exec'ed. The file is read
+// with the pod executor (see WithPodExecutor), collections fail before the job is applied without one
+func WithResultFromFile(path string) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.resultFilePath = path
+	}
+}
+
+// WithJobPodSpecMutator add a hook mutating the job pod spec, see WithPodSpecMutator
+func WithJobPodSpecMutator(mutator func(*corev1.PodSpec)) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.podSpecMutators = append(jc.podSpecMutators, mutator)
+	}
+}
+
+// WithCollectorJobMutator add a hook mutating the whole job, see WithJobMutator
+func WithCollectorJobMutator(mutator func(*batchv1.Job)) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.jobMutators = append(jc.jobMutators, mutator)
+	}
+}
+
+// WithOutputValidator validate collector output (e.g. against node-collector schema),
+// ApplyAndCollect returns the validation error rather than the output when it fails
+func WithOutputValidator(validator func([]byte) error) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.outputValidator = validator
+	}
+}
+
+// WithResultPostProcessor transform the validated output before it is returned (e.g. redact host paths),
+// multiple post-processors are applied in order
+func WithResultPostProcessor(postProcessor func([]byte) ([]byte, error)) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.resultPostProcessors = append(jc.resultPostProcessors, postProcessor)
+	}
+}
+
+func WithJobTolerateAll() CollectorOption {
+	return func(jc *jobCollector) {
+		jc.tolerateAll = true
+	}
+}
+
+// WithJobControlPlaneTolerations let collector jobs run on control-plane nodes, see WithControlPlaneTolerations
+func WithJobControlPlaneTolerations() CollectorOption {
+	return func(jc *jobCollector) {
+		jc.controlPlaneTolerations = true
+	}
+}
+
+func WithPodReadinessGates(readinessGates []corev1.PodReadinessGate) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.readinessGates = readinessGates
+	}
+}
+
+// WithJobEphemeralStorage set collector container ephemeral-storage, see WithEphemeralStorage
+func WithJobEphemeralStorage(request, limit resource.Quantity) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.ephemeralStorageRequest = request
+		jc.ephemeralStorageLimit = limit
+	}
+}
+
+// WithCollectorJobStrategicMergePatch apply a strategic merge patch to collector jobs, see WithJobStrategicMergePatch
+func WithCollectorJobStrategicMergePatch(patch []byte) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.strategicMergePatch = patch
+	}
+}
+
+// WithCollectorJobSpecOverlay merge a partial job spec over collector jobs spec, see WithJobSpecOverlay
+func WithCollectorJobSpecOverlay(overlay []byte) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.jobSpecOverlay = overlay
+	}
+}
+
+// WithCollectorRegistryMirror rewrite collector images registry to a mirror, see WithRegistryMirror
+func WithCollectorRegistryMirror(from, to string) CollectorOption {
+	return func(jc *jobCollector) {
+		if jc.registryMirrors == nil {
+			jc.registryMirrors = make(map[string]string)
 		}
+		jc.registryMirrors[from] = to
 	}
+}
 
-	JobOptions := []JobOption{
-		WithTemplate(jb.templateName),
-		WithNamespace(jb.namespace),
-		WithNodeName(nodeName),
-		WithAnnotation(jb.annotation),
-		WithLabels(jb.labels),
-		WithJobTimeout(jb.collectorTimeout),
-		withSecurityContext(jb.securityContext),
-		withPodSecurityContext(jb.podSecurityContext),
-		WithNodeCollectorImageRef(jb.imageRef),
-		WithAffinity(jb.affinity),
-		WithTolerations(jb.tolerations),
-		WithPodVolumes(jb.volumes),
-		WithImagePullSecrets(jb.imagePullSecrets),
-		WithContainerVolumeMounts(jb.volumeMounts),
-		WithNodeConfiguration(jb.nodeConfig),
-		WithPriorityClassName(jb.priorityClassName),
-		WithResourceRequirements(jb.resourceRequirements),
-		WithUseNodeSelectorParam(true),
-		WithJobName(fmt.Sprintf("%s-%s", jb.templateName, ComputeHash(
-			ObjectRef{
-				Kind:      "Node-Info",
-				Name:      nodeName,
-				Namespace: jb.namespace,
-			}))),
+// WithCollectorConfigSecret mount a secret read-only in collector jobs at mountPath, see WithConfigSecret
+func WithCollectorConfigSecret(secretName string, mountPath string) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.configSecrets = append(jc.configSecrets, configSecret{secretName: secretName, mountPath: mountPath})
 	}
-	if jb.nodeConfig {
-		JobOptions = append(JobOptions, WithJobServiceAccount(serviceAccount))
+}
+
+// WithPodAdditionalImagePullSecret append an image pull secret to collector pods, calls accumulate
+// unlike WithPodImagePullSecrets which replace the secrets
+func WithPodAdditionalImagePullSecret(name string) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.additionalImagePullSecrets = append(jc.additionalImagePullSecrets, name)
 	}
-	job, err := GetJob(JobOptions...)
-	if err != nil {
-		return "", fmt.Errorf("running node-collector job: %w", err)
+}
+
+// WithAdaptiveTimeout compute the job timeout per node (e.g. from node capacity),
+// it replaces WithCollectorTimeout for nodes that can be fetched
+func WithAdaptiveTimeout(timeoutFunc func(node corev1.Node) time.Duration) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.adaptiveTimeout = timeoutFunc
 	}
+}
 
-	err = New(WithTimeout(jb.timeout)).Run(ctx, NewRunnableJob(jb.cluster.GetK8sClientSet(), job))
-	if err != nil {
-		return "", fmt.Errorf("running node-collector job: %w", err)
+// WithCollectorServiceAccountAnnotations annotate the service account created for node config collection
+func WithCollectorServiceAccountAnnotations(annotations map[string]string) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.serviceAccountAnnotations = annotations
 	}
-	defer func() {
-		background := metav1.DeletePropagationBackground
-		if jb.nodeConfig {
-			_ = jb.cluster.GetK8sClientSet().RbacV1().ClusterRoleBindings().Delete(ctx, roleBinding, metav1.DeleteOptions{
-				PropagationPolicy: &background,
-			})
-			_ = jb.cluster.GetK8sClientSet().RbacV1().ClusterRoles().Delete(ctx, clusterRole, metav1.DeleteOptions{
-				PropagationPolicy: &background,
-			})
-			_ = jb.cluster.GetK8sClientSet().CoreV1().ServiceAccounts(job.Namespace).Delete(ctx, serviceAccount, metav1.DeleteOptions{
-				PropagationPolicy: &background,
-			})
-		}
-		_ = jb.cluster.GetK8sClientSet().BatchV1().Jobs(job.Namespace).Delete(ctx, job.Name, metav1.DeleteOptions{
-			PropagationPolicy: &background,
-		})
-	}()
+}
 
-	logsStream, err := jb.logsReader.GetLogsByJobAndContainerName(ctx, job, NodeCollectorName)
-	if err != nil {
-		return "", fmt.Errorf("getting logs: %w", err)
+// WithImagePullSecretsFromServiceAccount attach image pull secrets to the service account created
+// for node config collection rather than to the pod, collector pods inherit them from it
+func WithImagePullSecretsFromServiceAccount(imagePullSecrets []corev1.LocalObjectReference) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.serviceAccountImagePullSecrets = imagePullSecrets
 	}
-	defer func() {
-		_ = logsStream.Close()
-	}()
-	output, err := io.ReadAll(logsStream)
-	if err != nil {
-		return "", fmt.Errorf("reading logs: %w", err)
+}
+
+// WithRequireExistingNamespace do not create the collector namespace, the collection fail fast
+// with ErrNamespaceNotFound when it is missing, e.g. in clusters where namespaces are pre-provisioned
+func WithRequireExistingNamespace(requireExistingNamespace bool) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.requireExistingNamespace = requireExistingNamespace
 	}
-	return string(output), nil
 }
 
-// Apply deploy k8s job by template to specific node and namespace (for operator use case)
-func (jb *jobCollector) Apply(ctx context.Context, nodeName string) (*batchv1.Job, error) {
-	jobOptions := []JobOption{
-		WithNamespace(jb.namespace),
-		WithLabels(jb.labels),
-		withPodSecurityContext(jb.podSecurityContext),
-		withSecurityContext(jb.securityContext),
-		WithAffinity(jb.affinity),
-		WithTolerations(jb.tolerations),
-		WithJobServiceAccount(jb.serviceAccount),
-		WithJobTimeout(jb.collectorTimeout),
-		WithNodeCollectorImageRef(jb.imageRef),
-		WithAnnotation(jb.annotation),
-		WithTemplate(jb.templateName),
-		WithPodVolumes(jb.volumes),
-		WithNodeConfiguration(jb.nodeConfig),
-		WithImagePullSecrets(jb.imagePullSecrets),
-		WithContainerVolumeMounts(jb.volumeMounts),
-		WithPriorityClassName(jb.priorityClassName),
-		WithNodeName(nodeName),
-		WithJobName(jb.name),
-		WithUseNodeSelectorParam(jb.useNodeSelector),
-		WithResourceRequirements(jb.resourceRequirements)}
+// WithOwnNamespace let Cleanup delete the collector namespace even if it was not created by the collector
+func WithOwnNamespace(ownNamespace bool) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.ownNamespace = ownNamespace
+	}
+}
 
-	job, err := GetJob(jobOptions...)
-	if err != nil {
-		return nil, fmt.Errorf("running node-collector job: %w", err)
+// WithLogsReaderOptions configure the logs reader used to read collector output
+func WithLogsReaderOptions(opts ...LogsReaderOption) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.logsReader = NewLogsReader(jc.clientset, opts...)
 	}
-	// create job
-	job, err = jb.cluster.GetK8sClientSet().BatchV1().Jobs(job.Namespace).Create(ctx, job, metav1.CreateOptions{})
-	if err != nil {
-		return nil, err
+}
+
+// WithNamespaceReadyTimeout set how long to wait for a terminating collector namespace to be active again
+func WithNamespaceReadyTimeout(timeout time.Duration) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.namespaceReadyTimeout = timeout
 	}
-	return job, nil
 }
 
-func (jb *jobCollector) deleteTrivyNamespace(ctx context.Context) {
-	background := metav1.DeletePropagationBackground
-	_ = jb.cluster.GetK8sClientSet().CoreV1().Namespaces().Delete(ctx, jb.namespace, metav1.DeleteOptions{
-		PropagationPolicy: &background,
-	})
+// WithJobTTLSecondsAfterFinished set the ttl of jobs created by Apply after they finished, ApplyAndCollect
+// jobs are deleted once their output is read, see WithPodManagementFinalizer to guard them from garbage collection
+func WithJobTTLSecondsAfterFinished(ttl int32) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.ttlSecondsAfterFinished = &ttl
+	}
 }
 
-func (jb *jobCollector) getTrivyNamespace(ctx context.Context) (*corev1.Namespace, error) {
-	return jb.cluster.GetK8sClientSet().CoreV1().Namespaces().Get(ctx, jb.namespace, metav1.GetOptions{})
+// WithPodManagementFinalizer add PodManagementFinalizer to ApplyAndCollect pods so they are not garbage collected
+// (e.g. by a job ttl or owner deletion) before their output is read, it is removed once the collection is done
+func WithPodManagementFinalizer(podManagementFinalizer bool) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.podManagementFinalizer = podManagementFinalizer
+	}
 }
 
-func (jb *jobCollector) Cleanup(ctx context.Context) {
-	jb.deleteTrivyNamespace(ctx)
+// WithJobSelectorLabels set collector jobs pod selector labels, see WithSelectorLabels
+func WithJobSelectorLabels(selectorLabels map[string]string) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.selectorLabels = selectorLabels
+	}
+}
+
+// WithArgsTemplate add collector args rendered from a go template against the node at apply time,
+// e.g. --region={{ index .Labels "topology.kubernetes.io/region" }}, missing fields render empty
+func WithArgsTemplate(tmpl string) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.argsTemplate = tmpl
+	}
+}
+
+// WithBeforeCleanup set a hook called with the job before ApplyAndCollect delete it,
+// e.g. to record the pod final status and events
+func WithBeforeCleanup(hook func(ctx context.Context, job *batchv1.Job)) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.beforeCleanup = hook
+	}
+}
+
+// WithGracefulBatchShutdown drain in-flight collections on context cancel: running jobs
+// get up to drainTimeout to complete, jobs still running are then deleted. A context deadline is extended by drainTimeout
+func WithGracefulBatchShutdown(drainTimeout time.Duration) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.drainTimeout = drainTimeout
+	}
+}
+
+// WithOnJobFailed set a callback invoked with the failure reason when the collector job
+// reach the Failed condition (e.g. it exhausted its backoffLimit)
+func WithOnJobFailed(onJobFailed func(job *batchv1.Job, reason string)) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.onJobFailed = onJobFailed
+	}
+}
+
+// WithEvictionRetries recreate the collector job up to retries times when its pod is evicted
+// (e.g. on spot nodes), instead of relying on the job backoffLimit
+func WithEvictionRetries(retries int) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.evictionRetries = retries
+	}
+}
+
+// WithManagedAuth create and delete the node config rbac (default), when disabled the rbac
+// pre-installed with RenderAuthYAML is used, the collector job still run as its service account
+func WithManagedAuth(managedAuth bool) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.unmanagedAuth = !managedAuth
+	}
+}
+
+// WithCommonLabels add labels to every resource the collector create (namespace, rbac, job and its
+// ephemeral resources), e.g. for cost allocation, job labels take precedence
+func WithCommonLabels(labels map[string]string) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.commonLabels = labels
+	}
+}
+
+// WithCollectorNodeFlag set the flag passing the node name to the collector, see WithNodeFlag
+func WithCollectorNodeFlag(flag string) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.nodeFlag = flag
+	}
+}
+
+// WithCollectorOutputFormat set the collector output format, see WithOutputFormat
+func WithCollectorOutputFormat(format string) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.outputFormat = format
+	}
+}
+
+// WithJobTolerationSeconds set tolerationSeconds on the collector job NoExecute tolerations, see WithTolerationSeconds
+func WithJobTolerationSeconds(seconds int64) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.tolerationSeconds = &seconds
+	}
+}
+
+// WithJobNoDeadline clear the collector job active deadline for debugging sessions,
+// the collection still times out with WithTimetout
+func WithJobNoDeadline(noDeadline bool) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.noDeadline = noDeadline
+	}
+}
+
+// WithNonZeroExitResults return the output of a collector container exiting non-zero (e.g. partial failure)
+// with its code in Result.ExitCode instead of failing the collection, callers can tell clean from degraded results.
+// Only the job failure is ignored, timeouts and cancellation still fail the collection
+func WithNonZeroExitResults(nonZeroExitResults bool) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.nonZeroExitResults = nonZeroExitResults
+	}
+}
+
+// WithDeletePropagationPolicy set the propagation policy of the collector deletes (job, rbac, namespace, pods),
+// e.g. Foreground to block until the job pods are gone, default to Background
+func WithDeletePropagationPolicy(policy metav1.DeletionPropagation) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.deletePropagation = policy
+	}
+}
+
+// WithDeleteGracePeriod set the grace period seconds of the collector deletes,
+// e.g. 0 to delete immediately, default to the object grace period
+func WithDeleteGracePeriod(seconds *int64) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.deleteGracePeriod = seconds
+	}
+}
+
+// WithVersionCompatibilityCheck fail before the job is created when the node-collector
+// image tag version output is not supported, see ErrIncompatibleNodeCollector. The version is read
+// from the image tag, images without version tag fail with ErrUnknownNodeCollectorVersion
+func WithVersionCompatibilityCheck(versionCheck bool) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.versionCheck = versionCheck
+	}
+}
+
+// WithSchedulingTimeout fail the collection and delete the job when no collector pod is running
+// within timeout (e.g. the pod can't be scheduled or its image pulled), apart from the job timeout
+func WithSchedulingTimeout(timeout time.Duration) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.schedulingTimeout = timeout
+	}
+}
+
+// WithStartupArgs append args to the collector container on the first collection of a node only
+// (e.g. --init-cache), the node is collected before when WithResultCache has a result for it,
+// even if expired, without result cache every collection is a first one
+func WithStartupArgs(args []string) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.startupArgs = args
+	}
+}
+
+// WithNodeLabelsToCopy copy the listed labels of the target node onto the job (e.g. zone, instance-type),
+// labels missing on the node are skipped
+func WithNodeLabelsToCopy(keys []string) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.nodeLabelsToCopy = keys
+	}
+}
+
+// WithNodeAnnotationsToCopy copy the listed annotations of the target node onto the job,
+// annotations missing on the node are skipped
+func WithNodeAnnotationsToCopy(keys []string) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.nodeAnnotationsToCopy = keys
+	}
+}
+
+// contextAnnotation is a job annotation taken from a request context value
+type contextAnnotation struct {
+	annotation string
+	key        any
+	format     func(value any) string
+}
+
+// WithContextValuesPropagation add the context value of key as job annotation at apply time (e.g. a request id),
+// the value is formatted with format or fmt.Sprint when nil, the annotation is skipped when the context has no value
+func WithContextValuesPropagation(annotation string, key any, format func(value any) string) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.contextAnnotations = append(jc.contextAnnotations, contextAnnotation{annotation: annotation, key: key, format: format})
+	}
+}
+
+// WithResultCache cache ApplyAndCollect output per node and collector image for ttl,
+// a fresh cached output is returned without running a job
+func WithResultCache(ttl time.Duration) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.resultCache = newResultCache(ttl)
+	}
+}
+
+func NewCollector(
+	cluster k8s.Cluster,
+	opts ...CollectorOption,
+) Collector {
+	clientset := cluster.GetK8sClientSet()
+	jc := &jobCollector{
+		cluster:    cluster,
+		clientset:  clientset,
+		timeout:    0,
+		logsReader: NewLogsReader(clientset),
+		clock:      realClock{},
+		mu:         &sync.RWMutex{},
+		lifecycle:  &collectorLifecycle{},
+	}
+	if config := cluster.GetRestConfig(); config != nil {
+		jc.podExecutor = newSPDYPodExecutor(config, clientset)
+	}
+	for _, opt := range opts {
+		opt(jc)
+	}
+	return jc
+}
+
+// Close abort in-flight collections and reject further ones with ErrCollectorClosed,
+// the node informer given to the collector is owned by the caller and is not stopped
+func (jb *jobCollector) Close() error {
+	jb.lifecycle.close()
+	return nil
+}
+
+// collectorLifecycle hold the context cancelled on Close, it is shared by collector snapshots
+type collectorLifecycle struct {
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (l *collectorLifecycle) context() context.Context {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.ctx == nil {
+		l.ctx, l.cancel = context.WithCancel(context.Background())
+	}
+	return l.ctx
+}
+
+func (l *collectorLifecycle) close() {
+	l.context()
+	l.cancel()
+}
+
+func (l *collectorLifecycle) closed() bool {
+	return l.context().Err() != nil
+}
+
+// closable derive a context cancelled when the collector is closed
+func (jb *jobCollector) closable(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	if jb.lifecycle.closed() {
+		return nil, nil, ErrCollectorClosed
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(jb.lifecycle.context(), cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}, nil
+}
+
+// NewCollectorWithInformer instansiate a collector reading nodes from the shared informer cache
+// rather than getting them from the API server, the caller is responsible to start the informer
+func NewCollectorWithInformer(
+	cluster k8s.Cluster,
+	nodeInformer coreinformers.NodeInformer,
+	opts ...CollectorOption,
+) Collector {
+	return NewCollector(cluster, append(opts, WithNodeInformer(nodeInformer))...)
+}
+
+// WithNodeInformer read nodes from the informer cache, nodes missing from the cache are fetched from the API server
+func WithNodeInformer(nodeInformer coreinformers.NodeInformer) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.nodeLister = nodeInformer.Lister()
+	}
+}
+
+// snapshot returns a copy of the collector config, it is safe to use while options are appended
+func (jb *jobCollector) snapshot() *jobCollector {
+	jb.mu.RLock()
+	defer jb.mu.RUnlock()
+	c := *jb
+	c.labels = maps.Clone(jb.labels)
+	c.commonLabels = maps.Clone(jb.commonLabels)
+	c.annotation = maps.Clone(jb.annotation)
+	c.registryMirrors = maps.Clone(jb.registryMirrors)
+	c.containerResourceRequirements = maps.Clone(jb.containerResourceRequirements)
+	c.podSpecMutators = slices.Clone(jb.podSpecMutators)
+	c.jobMutators = slices.Clone(jb.jobMutators)
+	c.resultPostProcessors = slices.Clone(jb.resultPostProcessors)
+	c.configSecrets = slices.Clone(jb.configSecrets)
+	c.nodeLabelsToCopy = slices.Clone(jb.nodeLabelsToCopy)
+	c.nodeAnnotationsToCopy = slices.Clone(jb.nodeAnnotationsToCopy)
+	c.contextAnnotations = slices.Clone(jb.contextAnnotations)
+	c.additionalImagePullSecrets = slices.Clone(jb.additionalImagePullSecrets)
+	return &c
+}
+
+// AppendLabels Append labels to job
+func (jb *jobCollector) AppendLabels(opts ...CollectorOption) {
+	jb.mu.Lock()
+	defer jb.mu.Unlock()
+	for _, opt := range opts {
+		opt(jb)
+	}
+}
+
+// Result is the output of a collector job with the job metadata
+type Result struct {
+	Output   string
+	NodeName string
+	JobName  string
+	PodName  string
+	// JobStartTime and JobCompletionTime are the job status times
+	JobStartTime      time.Time
+	JobCompletionTime time.Time
+	// ExitCode of the collector container, it is non-zero only with WithNonZeroExitResults
+	ExitCode int32
+	// Checksum is the SHA-256 of the canonicalized output, it is equal for unchanged outputs
+	Checksum string
+	// StartedAt is when the job was applied and FinishedAt when its output was read, measured by the collector clock
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// CollectorJobInfo describe collector job and the node it was deployed to
+type CollectorJobInfo struct {
+	Name              string
+	NodeName          string
+	Status            string
+	CreationTimestamp time.Time
+}
+
+type ObjectRef struct {
+	Kind      string
+	Name      string
+	Namespace string
+}
+
+// ApplyAndCollect deploy k8s job by template to  specific node  and namespace, it read pod logs
+// cleaning up job and returning it output (for cli use-case)
+func (jb *jobCollector) ApplyAndCollect(ctx context.Context, nodeName string) (string, error) {
+	result, err := jb.ApplyAndCollectResult(ctx, nodeName)
+	if err != nil {
+		return "", err
+	}
+	return result.Output, nil
+}
+
+// ApplyAndCollectInNamespace is ApplyAndCollect with the job deployed in namespace rather than the collector namespace
+func (jb *jobCollector) ApplyAndCollectInNamespace(ctx context.Context, nodeName string, namespace string) (string, error) {
+	c := jb.snapshot()
+	c.namespace = namespace
+	return c.ApplyAndCollect(ctx, nodeName)
+}
+
+// ApplyAndCollectResult apply the collector job on the node and return its output with job metadata
+func (jb *jobCollector) ApplyAndCollectResult(ctx context.Context, nodeName string) (_ *Result, err error) {
+	jb = jb.snapshot()
+	if jb.optionErr != nil {
+		return nil, jb.optionErr
+	}
+	ctx, release, err := jb.closable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		release()
+		if err != nil && jb.lifecycle.closed() {
+			// collection aborted by Close
+			err = fmt.Errorf("%w: %w", ErrCollectorClosed, err)
+		}
+	}()
+	parentCtx := ctx
+	if jb.drainTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = drainContext(ctx, jb.clock, jb.drainTimeout)
+		defer cancel()
+	}
+	ctx, span := jb.tracer().Start(ctx, "ApplyAndCollect", trace.WithAttributes(attribute.String("node.name", nodeName)))
+	defer func() {
+		endSpan(span, err)
+	}()
+	cacheKey := resultCacheKey{nodeName: nodeName, imageRef: jb.imageRef}
+	if jb.resultCache != nil {
+		if result, ok := jb.resultCache.get(cacheKey, jb.clock.Now()); ok {
+			span.SetAttributes(attribute.Bool("cache.hit", true))
+			return result, nil
+		}
+	}
+	if len(jb.resultFilePath) > 0 && jb.podExecutor == nil {
+		// fail before running a job whose result can't be read
+		return nil, errors.New("reading result file: pod executor is not configured")
+	}
+	firstCollection := jb.resultCache == nil || !jb.resultCache.hasCollected(cacheKey)
+	run, err := jb.runCollectorJob(ctx, parentCtx, nodeName, firstCollection)
+	if err != nil {
+		return nil, err
+	}
+	defer run.finish()
+	job := run.job
+	jobAttr := attribute.String("job.name", job.Name)
+
+	var output []byte
+	err = jb.tracePhase(ctx, "logs", func(ctx context.Context) error {
+		if len(jb.resultFilePath) > 0 {
+			output, err = jb.ReadResultFile(ctx, job)
+		} else {
+			output, err = jb.readLogs(ctx, job)
+			if err == nil && len(bytes.TrimSpace(output)) == 0 {
+				err = jb.checkLogsAvailable(ctx, job)
+			}
+		}
+		if err != nil {
+			return jb.checkNodeRemoved(ctx, nodeName, err)
+		}
+		return nil
+	}, jobAttr)
+	if err != nil {
+		return nil, err
+	}
+	finishedAt := jb.clock.Now()
+	if jb.outputValidator != nil {
+		if err = jb.outputValidator(output); err != nil {
+			return nil, fmt.Errorf("validating output: %w", err)
+		}
+	}
+	for _, postProcess := range jb.resultPostProcessors {
+		if output, err = postProcess(output); err != nil {
+			return nil, fmt.Errorf("post-processing output: %w", err)
+		}
+	}
+	result := &Result{
+		Output:     string(output),
+		NodeName:   nodeName,
+		JobName:    job.Name,
+		Checksum:   outputChecksum(output),
+		StartedAt:  run.startedAt,
+		FinishedAt: finishedAt,
+	}
+	jb.setResultMetadata(ctx, job, result)
+	if jb.resultCache != nil {
+		jb.resultCache.set(cacheKey, result, jb.clock.Now())
+	}
+	return result, nil
+}
+
+// jobRun is a completed collector job run, finish clean up the job and its resources
+// and must be called once the job output is read
+type jobRun struct {
+	job       *batchv1.Job
+	startedAt time.Time
+	finish    func()
+}
+
+// runCollectorJob apply the collector job on the node and wait for its completion, parentCtx is the
+// collection context before drain. The job is cleaned up on failure
+func (jb *jobCollector) runCollectorJob(ctx, parentCtx context.Context, nodeName string, firstCollection bool) (*jobRun, error) {
+	if err := jb.checkNode(ctx, nodeName); err != nil {
+		return nil, err
+	}
+	err := jb.tracePhase(ctx, "namespace", jb.ensureTrivyNamespace)
+	if err != nil {
+		return nil, err
+	}
+	if jb.nodeConfig && !jb.unmanagedAuth {
+		err = jb.tracePhase(ctx, "rbac", jb.createAuth)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	startedAt := jb.clock.Now()
+	var job *batchv1.Job
+	err = jb.tracePhase(ctx, "apply", func(ctx context.Context) error {
+		job, err = jb.buildCollectorJob(ctx, nodeName, firstCollection)
+		if err != nil {
+			return err
+		}
+		return jb.createJobResources(ctx, job)
+	})
+	if err != nil {
+		if job != nil {
+			// resources may be created before a later apply step fails
+			jb.deleteJobResources(ctx, job)
+		}
+		return nil, err
+	}
+	jobAttr := attribute.String("job.name", job.Name)
+	trace.SpanFromContext(ctx).SetAttributes(jobAttr)
+
+	err = jb.tracePhase(ctx, "wait", func(ctx context.Context) error {
+		if err := jb.throttle(ctx); err != nil {
+			return err
+		}
+		runner := New(WithTimeout(jb.timeout), WithClock(jb.clock))
+		runnable := NewRunnableJob(jb.clientset, job, jb.runnableJobOptions()...)
+		var err error
+		if jb.schedulingTimeout > 0 {
+			err = jb.runWithSchedulingTimeout(ctx, runner, runnable, job)
+		} else {
+			err = runner.Run(ctx, runnable)
+		}
+		if err != nil {
+			return jb.checkNodeRemoved(ctx, nodeName, fmt.Errorf("running node-collector job: %w", err))
+		}
+		return nil
+	}, jobAttr)
+	if errors.Is(err, ErrJobFailed) && jb.nonZeroExitResults && jb.collectorExitCode(ctx, job) != 0 {
+		// degraded collection, the output is still read
+		err = nil
+	}
+	if err != nil {
+		jb.removePodFinalizer(ctx, job)
+		if errors.Is(err, ErrSchedulingTimeout) || (jb.drainTimeout > 0 && parentCtx.Err() != nil) {
+			// job was not scheduled or did not complete within the drain timeout
+			jb.cleanup(ctx, job)
+		}
+		jb.deleteJobResources(ctx, job)
+		return nil, err
+	}
+	return &jobRun{
+		job:       job,
+		startedAt: startedAt,
+		finish: func() {
+			jb.removePodFinalizer(ctx, job)
+			jb.cleanup(ctx, job)
+			jb.deleteJobResources(ctx, job)
+		},
+	}, nil
+}
+
+// buildCollectorJob returns the collector job of the node, startup args are added on the first collection
+func (jb *jobCollector) buildCollectorJob(ctx context.Context, nodeName string, firstCollection bool) (*batchv1.Job, error) {
+	jobOptions := jb.collectJobOptions(ctx, nodeName)
+	args, err := jb.renderArgs(ctx, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	if len(jb.startupArgs) > 0 && firstCollection {
+		args = append(args, jb.startupArgs...)
+	}
+	jobOptions = append(jobOptions, WithContainerArgs(args))
+	job, err := GetJob(jobOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("running node-collector job: %w", err)
+	}
+	if jb.podManagementFinalizer {
+		job.Spec.Template.Finalizers = append(job.Spec.Template.Finalizers, PodManagementFinalizer)
+	}
+	if jb.versionCheck {
+		if err := checkNodeCollectorVersion(job.Spec.Template.Spec.Containers[0].Image); err != nil {
+			return nil, err
+		}
+	}
+	return job, nil
+}
+
+// createJobResources create the ephemeral resources used by the job (pull secret, config payload)
+func (jb *jobCollector) createJobResources(ctx context.Context, job *batchv1.Job) error {
+	if jb.pullCredentials != nil {
+		usePullSecret(job)
+		if err := jb.createPullSecret(ctx, job); err != nil {
+			return err
+		}
+	}
+	if len(jb.stdinConfig) > 0 {
+		mountStdinConfig(job)
+		return jb.createStdinConfig(ctx, job, nil)
+	}
+	return nil
+}
+
+// deleteJobResources delete the ephemeral resources used by the job, even when ctx is cancelled
+func (jb *jobCollector) deleteJobResources(ctx context.Context, job *batchv1.Job) {
+	if len(jb.stdinConfig) > 0 {
+		jb.deleteStdinConfig(ctx, job)
+	}
+	if jb.pullCredentials != nil {
+		jb.deletePullSecret(ctx, job)
+	}
+}
+
+// runnableJobOptions returns the options of the collector runnable job
+func (jb *jobCollector) runnableJobOptions() []RunnableJobOption {
+	runnableJobOptions := []RunnableJobOption{
+		WithDeleteOptions(jb.deleteOptions()),
+		WithThrottle(jb.throttle),
+		WithRunnableJobClock(jb.clock),
+	}
+	if jb.onJobFailed != nil {
+		runnableJobOptions = append(runnableJobOptions, WithOnFailed(jb.onJobFailed))
+	}
+	if jb.evictionRetries > 0 {
+		runnableJobOptions = append(runnableJobOptions, WithPodEvictionRetries(jb.evictionRetries))
+	}
+	if len(jb.stdinConfig) > 0 {
+		runnableJobOptions = append(runnableJobOptions, WithOnCreated(jb.ownStdinConfig))
+	}
+	return runnableJobOptions
+}
+
+// runWithSchedulingTimeout run the job, failing with ErrSchedulingTimeout when no job pod
+// is running within the scheduling timeout. The job run is cancelled and waited for on failure,
+// so its watches don't outlive the collection
+func (jb *jobCollector) runWithSchedulingTimeout(ctx context.Context, runner Runner, runnable Runnable, job *batchv1.Job) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	runnableDone := make(chan struct{})
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- runner.Run(ctx, RunnableFunc(func(ctx context.Context) error {
+			defer close(runnableDone)
+			return runnable.Run(ctx)
+		}))
+	}()
+	scheduled := make(chan error, 1)
+	go func() {
+		scheduled <- jb.waitForPodRunning(ctx, job)
+	}()
+	select {
+	case err := <-runErr:
+		return err
+	case err := <-scheduled:
+		if err != nil {
+			cancel()
+			<-runErr
+			<-runnableDone
+			return err
+		}
+		return <-runErr
+	}
+}
+
+// waitForPodRunning poll the job pod until it is running (or already terminated)
+func (jb *jobCollector) waitForPodRunning(ctx context.Context, job *batchv1.Job) error {
+	pollInterval := jb.schedulingPollInterval
+	if pollInterval == 0 {
+		pollInterval = defaultSchedulingPollInterval
+	}
+	err := wait.PollUntilContextTimeout(ctx, pollInterval, jb.schedulingTimeout, false, func(ctx context.Context) (bool, error) {
+		// job or pod may not be created yet
+		pod, err := jb.getJobPod(ctx, job)
+		if err != nil {
+			return false, nil
+		}
+		switch pod.Status.Phase {
+		case corev1.PodRunning, corev1.PodSucceeded, corev1.PodFailed:
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: no pod running after %s", ErrSchedulingTimeout, jb.schedulingTimeout)
+	}
+	return nil
+}
+
+// cleanup delete the collector job and its rbac resources, even when ctx is cancelled
+// (e.g. the collection is aborted by Close)
+func (jb *jobCollector) cleanup(ctx context.Context, job *batchv1.Job) {
+	ctx = context.WithoutCancel(ctx)
+	if jb.beforeCleanup != nil {
+		jb.beforeCleanup(ctx, job)
+	}
+	if jb.nodeConfig && !jb.unmanagedAuth {
+		_ = jb.throttle(ctx)
+		_ = jb.clientset.RbacV1().ClusterRoleBindings().Delete(ctx, roleBinding, jb.deleteOptions())
+		_ = jb.throttle(ctx)
+		_ = jb.clientset.RbacV1().ClusterRoles().Delete(ctx, clusterRole, jb.deleteOptions())
+		_ = jb.throttle(ctx)
+		_ = jb.clientset.CoreV1().ServiceAccounts(job.Namespace).Delete(ctx, serviceAccount, jb.deleteOptions())
+	}
+	_ = jb.throttle(ctx)
+	_ = jb.clientset.BatchV1().Jobs(job.Namespace).Delete(ctx, job.Name, jb.deleteOptions())
+}
+
+// deleteOptions returns the options of the collector deletes
+func (jb *jobCollector) deleteOptions() metav1.DeleteOptions {
+	policy := metav1.DeletePropagationBackground
+	if len(jb.deletePropagation) > 0 {
+		policy = jb.deletePropagation
+	}
+	return metav1.DeleteOptions{PropagationPolicy: &policy, GracePeriodSeconds: jb.deleteGracePeriod}
+}
+
+// drainContext returns a context cancelled drainTimeout after ctx is cancelled (measured by clock),
+// so an in-flight collection get a chance to complete. The ctx deadline is kept, extended by drainTimeout
+func drainContext(ctx context.Context, clock Clock, drainTimeout time.Duration) (context.Context, context.CancelFunc) {
+	drainCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	if deadline, ok := ctx.Deadline(); ok {
+		drainCtx, cancel = context.WithDeadline(context.WithoutCancel(ctx), deadline.Add(drainTimeout))
+	}
+	stop := context.AfterFunc(ctx, func() {
+		select {
+		case <-clock.After(drainTimeout):
+			cancel()
+		case <-drainCtx.Done():
+		}
+	})
+	return drainCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// outputChecksum returns the hex SHA-256 of the output, JSON output is canonicalized first
+// (compact, sorted keys) so formatting and key order do not change the checksum
+func outputChecksum(output []byte) string {
+	canonical := bytes.TrimSpace(output)
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err == nil {
+		// output with trailing data (e.g. `{"a":1}}`) is not JSON
+		if _, err := decoder.Token(); errors.Is(err, io.EOF) {
+			if b, err := json.Marshal(value); err == nil {
+				canonical = b
+			}
+		}
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
+}
+
+// renderArgs render the args template against the node, args are split on white spaces
+func (jb *jobCollector) renderArgs(ctx context.Context, nodeName string) ([]string, error) {
+	if len(jb.argsTemplate) == 0 {
+		return nil, nil
+	}
+	tmpl, err := template.New("args").Option("missingkey=zero").Parse(jb.argsTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parsing args template: %w", err)
+	}
+	node, err := jb.getNode(ctx, nodeName)
+	if err != nil {
+		return nil, fmt.Errorf("getting node %q: %w", nodeName, err)
+	}
+	var args bytes.Buffer
+	if err = tmpl.Execute(&args, node); err != nil {
+		return nil, fmt.Errorf("rendering args template: %w", err)
+	}
+	return strings.Fields(args.String()), nil
+}
+
+// removePodFinalizer remove PodManagementFinalizer from the job pods once the collection is done,
+// even when ctx is cancelled. It is best effort, pods are left to be released manually on failure
+func (jb *jobCollector) removePodFinalizer(ctx context.Context, job *batchv1.Job) {
+	if !jb.podManagementFinalizer {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	selector, err := getJobPodsSelector(ctx, jb.clientset, job)
+	if err != nil {
+		return
+	}
+	podList, err := jb.clientset.CoreV1().Pods(job.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return
+	}
+	for _, pod := range podList.Items {
+		if !slices.Contains(pod.Finalizers, PodManagementFinalizer) {
+			continue
+		}
+		finalizers := slices.DeleteFunc(slices.Clone(pod.Finalizers), func(finalizer string) bool {
+			return finalizer == PodManagementFinalizer
+		})
+		// resource version guard finalizers added concurrently
+		patch, err := json.Marshal(map[string]any{"metadata": map[string]any{
+			"finalizers":      finalizers,
+			"resourceVersion": pod.ResourceVersion,
+		}})
+		if err != nil {
+			continue
+		}
+		if err = jb.throttle(ctx); err != nil {
+			return
+		}
+		_, _ = jb.clientset.CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	}
+}
+
+// setResultMetadata set job times, pod name and exit code on the result,
+// metadata is best effort and does not fail the collection
+func (jb *jobCollector) setResultMetadata(ctx context.Context, job *batchv1.Job, result *Result) {
+	refreshedJob, err := jb.clientset.BatchV1().Jobs(job.Namespace).Get(ctx, job.Name, metav1.GetOptions{})
+	if err == nil {
+		if refreshedJob.Status.StartTime != nil {
+			result.JobStartTime = refreshedJob.Status.StartTime.Time
+		}
+		if refreshedJob.Status.CompletionTime != nil {
+			result.JobCompletionTime = refreshedJob.Status.CompletionTime.Time
+		}
+	}
+	pod, err := jb.getJobPod(ctx, job)
+	if err != nil {
+		return
+	}
+	result.PodName = pod.Name
+	if terminated, ok := GetTerminatedContainersStatusesByPod(pod)[NodeCollectorName]; ok {
+		result.ExitCode = terminated.ExitCode
+	}
+}
+
+// collectorExitCode returns the exit code of the terminated collector container, 0 when it is not terminated
+func (jb *jobCollector) collectorExitCode(ctx context.Context, job *batchv1.Job) int32 {
+	pod, err := jb.getJobPod(ctx, job)
+	if err != nil {
+		return 0
+	}
+	if terminated, ok := GetTerminatedContainersStatusesByPod(pod)[NodeCollectorName]; ok {
+		return terminated.ExitCode
+	}
+	return 0
+}
+
+// checkLogsAvailable returns ErrLogsUnavailable when the job pod no longer exists,
+// its empty logs are then not the collector output
+func (jb *jobCollector) checkLogsAvailable(ctx context.Context, job *batchv1.Job) error {
+	if _, err := jb.getJobPod(ctx, job); errors.Is(err, podControlledByJobNotFoundErr) {
+		return fmt.Errorf("job %q: %w", job.Namespace+"/"+job.Name, ErrLogsUnavailable)
+	}
+	return nil
+}
+
+// ensureTrivyNamespace create the collector namespace when it does not exist
+func (jb *jobCollector) ensureTrivyNamespace(ctx context.Context) error {
+	if jb.requireExistingNamespace {
+		return jb.checkTrivyNamespace(ctx)
+	}
+	timeout := jb.namespaceReadyTimeout
+	if timeout == 0 {
+		timeout = defaultNamespaceReadyTimeout
+	}
+	pollInterval := jb.namespacePollInterval
+	if pollInterval == 0 {
+		pollInterval = defaultNamespacePollInterval
+	}
+	// a namespace left terminating by a prior run reject creates, wait until it is gone and re-create it
+	err := wait.PollUntilContextTimeout(ctx, pollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		ns, err := jb.getTrivyNamespace(ctx)
+		if err != nil {
+			if !k8sapierror.IsNotFound(err) {
+				// namespace may not be readable, try to proceed
+				return true, nil
+			}
+			// mark the namespace so Cleanup only delete namespaces it created
+			namespaceLabels := maps.Clone(jb.commonLabels)
+			if namespaceLabels == nil {
+				namespaceLabels = make(map[string]string)
+			}
+			namespaceLabels[TrivyAutoCreated] = "true"
+			trivyNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:   jb.namespace,
+				Labels: namespaceLabels,
+			}}
+			if err = jb.throttle(ctx); err != nil {
+				return false, err
+			}
+			_, err = jb.clientset.CoreV1().Namespaces().Create(ctx, trivyNamespace, metav1.CreateOptions{})
+			if k8sapierror.IsAlreadyExists(err) {
+				return false, nil
+			}
+			return err == nil, err
+		}
+		return ns.Status.Phase != corev1.NamespaceTerminating, nil
+	})
+	if wait.Interrupted(err) {
+		return fmt.Errorf("waiting for namespace %q to be active: %w", jb.namespace, err)
+	}
+	return err
+}
+
+// checkTrivyNamespace returns ErrNamespaceNotFound when the collector namespace is missing or being deleted
+func (jb *jobCollector) checkTrivyNamespace(ctx context.Context) error {
+	ns, err := jb.getTrivyNamespace(ctx)
+	if k8sapierror.IsNotFound(err) || (err == nil && ns.Status.Phase == corev1.NamespaceTerminating) {
+		return fmt.Errorf("%w: %q must be created before running the collector", ErrNamespaceNotFound, jb.namespace)
+	}
+	// namespace may not be readable, try to proceed
+	return nil
+}
+
+// createAuth create node-collector cluster role, service account and role binding
+func (jb *jobCollector) createAuth(ctx context.Context) error {
+	cr, rb, sa, err := GetAuth(
+		WithServiceAccountNamespace(jb.namespace),
+		WithServiceAccountAnnotations(jb.serviceAccountAnnotations),
+		WithServiceAccountImagePullSecrets(jb.serviceAccountImagePullSecrets),
+		WithAuthLabels(jb.commonLabels))
+	if err != nil {
+		return fmt.Errorf("running node-collector job: %w", err)
+	}
+	if err = jb.throttle(ctx); err != nil {
+		return err
+	}
+	_, err = jb.clientset.RbacV1().ClusterRoles().Create(ctx, cr, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("creating cluster role: %w", err)
+	}
+	if err = jb.throttle(ctx); err != nil {
+		return err
+	}
+	_, err = jb.clientset.CoreV1().ServiceAccounts(jb.namespace).Create(ctx, sa, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("creating service account: %w", err)
+	}
+	if err = jb.throttle(ctx); err != nil {
+		return err
+	}
+	_, err = jb.clientset.RbacV1().ClusterRoleBindings().Create(ctx, rb, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("creating role binding: %w", err)
+	}
+	return nil
+}
+
+// collectJobOptions returns the job options of ApplyAndCollect
+func (jb *jobCollector) collectJobOptions(ctx context.Context, nodeName string) []JobOption {
+	jobOptions := []JobOption{
+		WithTemplate(jb.templateName),
+		WithNamespace(jb.namespace),
+		WithNodeName(nodeName),
+		WithSelectorLabels(jb.selectorLabels),
+		WithAnnotation(jb.jobAnnotations(ctx, nodeName)),
+		WithLabels(jb.jobLabels(ctx, nodeName)),
+		WithJobTimeout(jb.jobTimeout(ctx, nodeName)),
+		WithNoDeadline(jb.noDeadline),
+		WithOutputFormat(jb.outputFormat),
+		WithNodeFlag(jb.nodeFlag),
+		withSecurityContext(jb.securityContext),
+		withPodSecurityContext(jb.podSecurityContext),
+		WithNodeCollectorImageRef(jb.imageRef),
+		WithAffinity(jb.affinity),
+		WithPodAffinity(jb.podAffinity),
+		WithReadinessGates(jb.readinessGates),
+		WithEphemeralStorage(jb.ephemeralStorageRequest, jb.ephemeralStorageLimit),
+		WithTolerations(jb.tolerations),
+		WithPodVolumes(jb.volumes),
+		WithImagePullSecrets(jb.imagePullSecrets),
+		WithContainerVolumeMounts(jb.volumeMounts),
+		WithNodeConfiguration(jb.nodeConfig),
+		WithPriorityClassName(jb.priorityClassName),
+		WithPriority(jb.priority),
+		WithResourceRequirements(jb.resourceRequirements),
+		WithUseNodeSelectorParam(true),
+		WithHostNetwork(jb.hostNetwork),
+		WithDNSPolicy(jb.dnsPolicy),
+		WithDNSConfig(jb.dnsConfig),
+		WithNodeArch(jb.nodeArch),
+		WithJobName(fmt.Sprintf("%s-%s", jb.templateName, ComputeHash(
+			ObjectRef{
+				Kind:      "Node-Info",
+				Name:      nodeName,
+				Namespace: jb.namespace,
+			}))),
+	}
+	if jb.nodeConfig {
+		jobOptions = append(jobOptions, WithJobServiceAccount(serviceAccount))
+	}
+	for containerName, rr := range jb.containerResourceRequirements {
+		jobOptions = append(jobOptions, WithResourceRequirementsForContainer(containerName, rr))
+	}
+	if jb.controlPlaneTolerations {
+		jobOptions = append(jobOptions, WithControlPlaneTolerations())
+	}
+	if jb.tolerateAll {
+		jobOptions = append(jobOptions, WithTolerateAll())
+	}
+	if jb.tolerationSeconds != nil {
+		jobOptions = append(jobOptions, WithTolerationSeconds(*jb.tolerationSeconds))
+	}
+	for _, cs := range jb.configSecrets {
+		jobOptions = append(jobOptions, WithConfigSecret(cs.secretName, cs.mountPath))
+	}
+	for _, name := range jb.additionalImagePullSecrets {
+		jobOptions = append(jobOptions, WithAdditionalImagePullSecret(name))
+	}
+	if len(jb.jobSpecOverlay) > 0 {
+		jobOptions = append(jobOptions, WithJobSpecOverlay(jb.jobSpecOverlay))
+	}
+	for from, to := range jb.registryMirrors {
+		jobOptions = append(jobOptions, WithRegistryMirror(from, to))
+	}
+	if len(jb.strategicMergePatch) > 0 {
+		jobOptions = append(jobOptions, WithJobStrategicMergePatch(jb.strategicMergePatch))
+	}
+	for _, mutator := range jb.podSpecMutators {
+		jobOptions = append(jobOptions, WithPodSpecMutator(mutator))
+	}
+	for _, mutator := range jb.jobMutators {
+		jobOptions = append(jobOptions, WithJobMutator(mutator))
+	}
+	return jobOptions
+}
+
+// ReadResultFile read the collector result file by exec'ing cat in the collector container
+func (jb *jobCollector) ReadResultFile(ctx context.Context, job *batchv1.Job) ([]byte, error) {
+	jb = jb.snapshot()
+	if jb.podExecutor == nil {
+		return nil, errors.New("reading result file: pod executor is not configured")
+	}
+	if len(jb.resultFilePath) == 0 {
+		return nil, errors.New("reading result file: result file path is not configured")
+	}
+	pod, err := jb.getJobPod(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	err = jb.podExecutor.Exec(ctx, pod.Namespace, pod.Name, NodeCollectorName, []string{"cat", jb.resultFilePath}, nil, &stdout, &stderr)
+	if err != nil {
+		return nil, fmt.Errorf("reading result file %q: %w: %s", jb.resultFilePath, err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// ExecInCollector run the command in the collector pod container without stdin and tty,
+// it returns the command stdout and stderr (for troubleshooting)
+func (jb *jobCollector) ExecInCollector(ctx context.Context, job *batchv1.Job, container string, cmd []string) (string, string, error) {
+	jb = jb.snapshot()
+	if jb.podExecutor == nil {
+		return "", "", errors.New("exec in collector: pod executor is not configured")
+	}
+	pod, err := jb.getJobPod(ctx, job)
+	if err != nil {
+		return "", "", err
+	}
+	var stdout, stderr bytes.Buffer
+	err = jb.podExecutor.Exec(ctx, pod.Namespace, pod.Name, container, cmd, nil, &stdout, &stderr)
+	if err != nil {
+		return stdout.String(), stderr.String(), fmt.Errorf("exec in collector pod %q: %w", pod.Namespace+"/"+pod.Name, err)
+	}
+	return stdout.String(), stderr.String(), nil
+}
+
+// getJobPod returns the first pod controlled by the job
+func (jb *jobCollector) getJobPod(ctx context.Context, job *batchv1.Job) (*corev1.Pod, error) {
+	selector, err := getJobPodsSelector(ctx, jb.clientset, job)
+	if err != nil {
+		return nil, fmt.Errorf("getting job pods selector: %w", err)
+	}
+	podList, err := jb.clientset.CoreV1().Pods(job.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("listing job pods: %w", err)
+	}
+	if len(podList.Items) == 0 {
+		return nil, fmt.Errorf("getting pod controlled by job: %q: %w", job.Namespace+"/"+job.Name, podControlledByJobNotFoundErr)
+	}
+	return &podList.Items[0], nil
+}
+
+// readLogs read collector container logs, up to maxLogBytes when set.
+// the stream is closed once logReadTimeout elapse, as the logs stream may not respect the context
+func (jb *jobCollector) readLogs(ctx context.Context, job *batchv1.Job) ([]byte, error) {
+	logsStream, err := jb.openLogsStream(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = logsStream.Close()
+	}()
+	return io.ReadAll(logsStream)
+}
+
+// Apply deploy k8s job by template to specific node and namespace (for operator use case)
+func (jb *jobCollector) Apply(ctx context.Context, nodeName string) (*batchv1.Job, error) {
+	jb = jb.snapshot()
+	if jb.optionErr != nil {
+		return nil, jb.optionErr
+	}
+	ctx, release, err := jb.closable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := jb.checkNode(ctx, nodeName); err != nil {
+		return nil, err
+	}
+	job, err := jb.buildJob(ctx, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	// create job
+	if err = jb.throttle(ctx); err != nil {
+		return nil, err
+	}
+	job, err = jb.clientset.BatchV1().Jobs(job.Namespace).Create(ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// buildJob returns the job created by Apply
+func (jb *jobCollector) buildJob(ctx context.Context, nodeName string) (*batchv1.Job, error) {
+	jobOptions := []JobOption{
+		WithNamespace(jb.namespace),
+		WithLabels(jb.jobLabels(ctx, nodeName)),
+		withPodSecurityContext(jb.podSecurityContext),
+		withSecurityContext(jb.securityContext),
+		WithAffinity(jb.affinity),
+		WithPodAffinity(jb.podAffinity),
+		WithReadinessGates(jb.readinessGates),
+		WithEphemeralStorage(jb.ephemeralStorageRequest, jb.ephemeralStorageLimit),
+		WithTolerations(jb.tolerations),
+		WithJobServiceAccount(jb.serviceAccount),
+		WithJobTimeout(jb.jobTimeout(ctx, nodeName)),
+		WithNoDeadline(jb.noDeadline),
+		WithOutputFormat(jb.outputFormat),
+		WithNodeFlag(jb.nodeFlag),
+		WithNodeCollectorImageRef(jb.imageRef),
+		WithAnnotation(jb.jobAnnotations(ctx, nodeName)),
+		WithTemplate(jb.templateName),
+		WithPodVolumes(jb.volumes),
+		WithNodeConfiguration(jb.nodeConfig),
+		WithImagePullSecrets(jb.imagePullSecrets),
+		WithContainerVolumeMounts(jb.volumeMounts),
+		WithPriorityClassName(jb.priorityClassName),
+		WithPriority(jb.priority),
+		WithNodeName(nodeName),
+		WithSelectorLabels(jb.selectorLabels),
+		WithJobName(jb.name),
+		WithUseNodeSelectorParam(jb.useNodeSelector),
+		WithResourceRequirements(jb.resourceRequirements),
+		WithHostNetwork(jb.hostNetwork),
+		WithDNSPolicy(jb.dnsPolicy),
+		WithDNSConfig(jb.dnsConfig),
+		WithSuspend(jb.suspend),
+		WithNodeArch(jb.nodeArch),
+		WithTTLSecondsAfterFinished(jb.ttlSecondsAfterFinished)}
+	for containerName, rr := range jb.containerResourceRequirements {
+		jobOptions = append(jobOptions, WithResourceRequirementsForContainer(containerName, rr))
+	}
+	if jb.controlPlaneTolerations {
+		jobOptions = append(jobOptions, WithControlPlaneTolerations())
+	}
+	if jb.tolerateAll {
+		jobOptions = append(jobOptions, WithTolerateAll())
+	}
+	if jb.tolerationSeconds != nil {
+		jobOptions = append(jobOptions, WithTolerationSeconds(*jb.tolerationSeconds))
+	}
+	for _, cs := range jb.configSecrets {
+		jobOptions = append(jobOptions, WithConfigSecret(cs.secretName, cs.mountPath))
+	}
+	for _, name := range jb.additionalImagePullSecrets {
+		jobOptions = append(jobOptions, WithAdditionalImagePullSecret(name))
+	}
+	if len(jb.jobSpecOverlay) > 0 {
+		jobOptions = append(jobOptions, WithJobSpecOverlay(jb.jobSpecOverlay))
+	}
+	for from, to := range jb.registryMirrors {
+		jobOptions = append(jobOptions, WithRegistryMirror(from, to))
+	}
+	if len(jb.strategicMergePatch) > 0 {
+		jobOptions = append(jobOptions, WithJobStrategicMergePatch(jb.strategicMergePatch))
+	}
+	for _, mutator := range jb.podSpecMutators {
+		jobOptions = append(jobOptions, WithPodSpecMutator(mutator))
+	}
+	for _, mutator := range jb.jobMutators {
+		jobOptions = append(jobOptions, WithJobMutator(mutator))
+	}
+	args, err := jb.renderArgs(ctx, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	jobOptions = append(jobOptions, WithContainerArgs(args))
+
+	job, err := GetJob(jobOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("running node-collector job: %w", err)
+	}
+	return job, nil
+}
+
+// checkNode verify the node can have the job scheduled on
+func (jb *jobCollector) checkNode(ctx context.Context, nodeName string) error {
+	if !jb.checkUnschedulable {
+		return nil
+	}
+	node, err := jb.getNode(ctx, nodeName)
+	if err != nil {
+		return fmt.Errorf("getting node %q: %w", nodeName, err)
+	}
+	if node.Spec.Unschedulable {
+		return fmt.Errorf("node %q: %w", nodeName, ErrNodeUnschedulable)
+	}
+	return nil
+}
+
+// checkNodeRemoved replace err with ErrNodeRemoved when the node no longer exists,
+// the collector pod is evicted in that case and the original error is confusing
+func (jb *jobCollector) checkNodeRemoved(ctx context.Context, nodeName string, err error) error {
+	if _, getErr := jb.getNode(ctx, nodeName); k8sapierror.IsNotFound(getErr) {
+		return fmt.Errorf("node %q: %w", nodeName, ErrNodeRemoved)
+	}
+	return err
+}
+
+func (jb *jobCollector) getNode(ctx context.Context, nodeName string) (*corev1.Node, error) {
+	if jb.nodeLister != nil {
+		node, err := jb.nodeLister.Get(nodeName)
+		if err == nil {
+			// cache objects are shared
+			return node.DeepCopy(), nil
+		}
+	}
+	return jb.clientset.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+}
+
+// jobAnnotations returns the job annotations with the context values to propagate and the node annotations to copy,
+// node annotations are skipped when the node can't be fetched
+func (jb *jobCollector) jobAnnotations(ctx context.Context, nodeName string) map[string]string {
+	annotations := jb.annotation
+	if len(jb.contextAnnotations) > 0 {
+		annotations = maps.Clone(jb.annotation)
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		for _, ca := range jb.contextAnnotations {
+			value := ctx.Value(ca.key)
+			if value == nil {
+				continue
+			}
+			if ca.format != nil {
+				annotations[ca.annotation] = ca.format(value)
+			} else {
+				annotations[ca.annotation] = fmt.Sprint(value)
+			}
+		}
+	}
+	if len(jb.nodeAnnotationsToCopy) == 0 {
+		return annotations
+	}
+	node, err := jb.getNode(ctx, nodeName)
+	if err != nil {
+		return annotations
+	}
+	return copyKeys(annotations, node.Annotations, jb.nodeAnnotationsToCopy)
+}
+
+// jobLabels returns the job labels with the common labels and the node labels to copy,
+// node labels are skipped when the node can't be fetched
+func (jb *jobCollector) jobLabels(ctx context.Context, nodeName string) map[string]string {
+	jobLabels := jb.labels
+	if len(jb.commonLabels) > 0 {
+		jobLabels = maps.Clone(jb.commonLabels)
+		maps.Copy(jobLabels, jb.labels)
+	}
+	if len(jb.nodeLabelsToCopy) == 0 {
+		return jobLabels
+	}
+	node, err := jb.getNode(ctx, nodeName)
+	if err != nil {
+		return jobLabels
+	}
+	return copyKeys(jobLabels, node.Labels, jb.nodeLabelsToCopy)
+}
+
+// copyKeys returns a copy of dst with the keys of src, keys missing in src are skipped
+func copyKeys(dst, src map[string]string, keys []string) map[string]string {
+	dst = maps.Clone(dst)
+	if dst == nil {
+		dst = make(map[string]string)
+	}
+	for _, key := range keys {
+		if val, ok := src[key]; ok {
+			dst[key] = val
+		}
+	}
+	return dst
+}
+
+// jobTimeout returns the job active deadline duration
+func (jb *jobCollector) jobTimeout(ctx context.Context, nodeName string) time.Duration {
+	collectorTimeout := jb.nodeCollectorTimeout(ctx, nodeName)
+	if !jb.deadlineFromContext {
+		return collectorTimeout
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return collectorTimeout
+	}
+	remaining := deadline.Sub(jb.clock.Now())
+	// active deadline seconds must be a positive value
+	if remaining < time.Second {
+		remaining = time.Second
+	}
+	if collectorTimeout > 0 && collectorTimeout < remaining {
+		return collectorTimeout
+	}
+	return remaining
+}
+
+// nodeCollectorTimeout returns the adaptive timeout of the node,
+// it falls back to the collector timeout when it is not set or the node can't be fetched
+func (jb *jobCollector) nodeCollectorTimeout(ctx context.Context, nodeName string) time.Duration {
+	if jb.adaptiveTimeout == nil {
+		return jb.collectorTimeout
+	}
+	node, err := jb.getNode(ctx, nodeName)
+	if err != nil {
+		return jb.collectorTimeout
+	}
+	return jb.adaptiveTimeout(*node)
+}
+
+func (jb *jobCollector) tracer() trace.Tracer {
+	tp := jb.tracerProvider
+	if tp == nil {
+		tp = noop.NewTracerProvider()
+	}
+	return tp.Tracer(tracerName)
+}
+
+// tracePhase run a collection phase within a child span
+func (jb *jobCollector) tracePhase(ctx context.Context, name string, fn func(ctx context.Context) error, attrs ...attribute.KeyValue) error {
+	ctx, span := jb.tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+	err := fn(ctx)
+	endSpan(span, err)
+	return err
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// throttle blocks until the rate limiter (if any) permits an API mutating call,
+// the wait is measured by the collector clock
+func (jb *jobCollector) throttle(ctx context.Context) error {
+	if jb.rateLimiter == nil {
+		return nil
+	}
+	reservation := jb.rateLimiter.ReserveN(jb.clock.Now(), 1)
+	if !reservation.OK() {
+		return fmt.Errorf("qps limit burst %d must be at least 1", jb.rateLimiter.Burst())
+	}
+	delay := reservation.DelayFrom(jb.clock.Now())
+	if delay == 0 {
+		return nil
+	}
+	select {
+	case <-jb.clock.After(delay):
+		return nil
+	case <-ctx.Done():
+		reservation.CancelAt(jb.clock.Now())
+		return ctx.Err()
+	}
+}
+
+func (jb *jobCollector) deleteTrivyNamespace(ctx context.Context) {
+	_ = jb.throttle(ctx)
+	_ = jb.clientset.CoreV1().Namespaces().Delete(ctx, jb.namespace, jb.deleteOptions())
+}
+
+func (jb *jobCollector) getTrivyNamespace(ctx context.Context) (*corev1.Namespace, error) {
+	return jb.clientset.CoreV1().Namespaces().Get(ctx, jb.namespace, metav1.GetOptions{})
+}
+
+// Cleanup delete the collector namespace when it was created by the collector,
+// a pre-existing namespace is kept unless WithOwnNamespace is set
+func (jb *jobCollector) Cleanup(ctx context.Context) {
+	jb = jb.snapshot()
+	if !jb.ownsTrivyNamespace(ctx) {
+		return
+	}
+	jb.deleteTrivyNamespace(ctx)
+}
+
+// CleanupAndWaitNamespace delete the collector namespace like Cleanup and wait until it is gone,
+// so a following run does not fail to re-create it while it is terminating
+func (jb *jobCollector) CleanupAndWaitNamespace(ctx context.Context, timeout time.Duration) error {
+	jb = jb.snapshot()
+	if !jb.ownsTrivyNamespace(ctx) {
+		return nil
+	}
+	jb.deleteTrivyNamespace(ctx)
+	pollInterval := jb.namespacePollInterval
+	if pollInterval == 0 {
+		pollInterval = defaultNamespacePollInterval
+	}
+	err := wait.PollUntilContextTimeout(ctx, pollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		_, err := jb.getTrivyNamespace(ctx)
+		// namespace finalizers may still be running, keep waiting on other errors
+		return k8sapierror.IsNotFound(err), nil
+	})
+	if wait.Interrupted(err) {
+		return fmt.Errorf("waiting for namespace %q to be deleted: %w", jb.namespace, err)
+	}
+	return err
+}
+
+// ownsTrivyNamespace returns whether the collector namespace can be deleted by the collector
+func (jb *jobCollector) ownsTrivyNamespace(ctx context.Context) bool {
+	if jb.ownNamespace {
+		return true
+	}
+	ns, err := jb.getTrivyNamespace(ctx)
+	return err == nil && ns.Labels[TrivyAutoCreated] == "true"
+}
+
+// ValidateJob validate the job against the cluster admission (e.g. PodSecurity) using a server-side dry-run create
+func (jb *jobCollector) ValidateJob(ctx context.Context, job *batchv1.Job) error {
+	_, err := jb.clientset.BatchV1().Jobs(job.Namespace).Create(ctx, job, metav1.CreateOptions{
+		DryRun: []string{metav1.DryRunAll},
+	})
+	if err != nil {
+		return fmt.Errorf("validating job %q: %w", job.Namespace+"/"+job.Name, err)
+	}
+	return nil
+}
+
+// ResumeJob resume a job created in suspended state
+func (jb *jobCollector) ResumeJob(ctx context.Context, job *batchv1.Job) error {
+	jb = jb.snapshot()
+	if err := jb.throttle(ctx); err != nil {
+		return err
+	}
+	_, err := jb.clientset.BatchV1().Jobs(job.Namespace).Patch(ctx, job.Name, types.MergePatchType,
+		[]byte(`{"spec":{"suspend":false}}`), metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("resuming job %q: %w", job.Namespace+"/"+job.Name, err)
+	}
+	return nil
+}
+
+// GetJobEvents returns events involving the job and its pods (e.g. FailedScheduling, ImagePullBackOff)
+func (jb *jobCollector) GetJobEvents(ctx context.Context, job *batchv1.Job) ([]corev1.Event, error) {
+	jb = jb.snapshot()
+	selector, err := getJobPodsSelector(ctx, jb.clientset, job)
+	if err != nil {
+		return nil, fmt.Errorf("getting job pods selector: %w", err)
+	}
+	podList, err := jb.clientset.CoreV1().Pods(job.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("listing job pods: %w", err)
+	}
+	// events are selected server-side, a namespace may have many events
+	involvedObjects := []fields.Set{{"involvedObject.kind": "Job", "involvedObject.name": job.Name}}
+	for _, pod := range podList.Items {
+		involvedObjects = append(involvedObjects, fields.Set{"involvedObject.kind": "Pod", "involvedObject.uid": string(pod.UID)})
+	}
+	events := make([]corev1.Event, 0)
+	for _, involvedObject := range involvedObjects {
+		eventList, err := jb.clientset.CoreV1().Events(job.Namespace).List(ctx, metav1.ListOptions{
+			FieldSelector: involvedObject.AsSelector().String(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("listing events: %w", err)
+		}
+		events = append(events, eventList.Items...)
+	}
+	return events, nil
+}
+
+// CleanupPods delete the pods controlled by the job, the job itself is kept (e.g. for audit)
+func (jb *jobCollector) CleanupPods(ctx context.Context, job *batchv1.Job) error {
+	jb = jb.snapshot()
+	selector, err := getJobPodsSelector(ctx, jb.clientset, job)
+	if err != nil {
+		return fmt.Errorf("getting job pods selector: %w", err)
+	}
+	podList, err := jb.clientset.CoreV1().Pods(job.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return fmt.Errorf("listing job pods: %w", err)
+	}
+	for _, pod := range podList.Items {
+		if err = jb.throttle(ctx); err != nil {
+			return err
+		}
+		err = jb.clientset.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, jb.deleteOptions())
+		if err != nil && !k8sapierror.IsNotFound(err) {
+			return fmt.Errorf("deleting pod %q: %w", pod.Namespace+"/"+pod.Name, err)
+		}
+	}
+	return nil
+}
+
+// ListCollectorJobs list collector jobs in the collector namespace and map them back to their nodes
+func (jb *jobCollector) ListCollectorJobs(ctx context.Context) ([]CollectorJobInfo, error) {
+	jb = jb.snapshot()
+	jobList, err := jb.clientset.BatchV1().Jobs(jb.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: TrivyCollectorName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing collector jobs: %w", err)
+	}
+	jobInfos := make([]CollectorJobInfo, 0, len(jobList.Items))
+	for _, job := range jobList.Items {
+		jobInfos = append(jobInfos, CollectorJobInfo{
+			Name:              job.Name,
+			NodeName:          job.Labels[TrivyResourceName],
+			Status:            string(jobStatus(&job)),
+			CreationTimestamp: job.CreationTimestamp.Time,
+		})
+	}
+	return jobInfos, nil
+}
+
+// CleanupJobs delete the collector jobs of the namespace and their pods, selector scope the deleted jobs
+// (e.g. to an instance label when trivy deployments share the namespace), nil selector delete all collector jobs
+func (jb *jobCollector) CleanupJobs(ctx context.Context, selector labels.Selector) error {
+	jb = jb.snapshot()
+	requirement, err := labels.NewRequirement(TrivyCollectorName, selection.Exists, nil)
+	if err != nil {
+		return err
+	}
+	jobsSelector := labels.NewSelector().Add(*requirement)
+	if selector != nil {
+		requirements, selectable := selector.Requirements()
+		if !selectable {
+			// selector match nothing
+			return nil
+		}
+		jobsSelector = jobsSelector.Add(requirements...)
+	}
+	jobList, err := jb.clientset.BatchV1().Jobs(jb.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: jobsSelector.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("listing collector jobs: %w", err)
+	}
+	for _, job := range jobList.Items {
+		if err = jb.throttle(ctx); err != nil {
+			return err
+		}
+		err = jb.clientset.BatchV1().Jobs(job.Namespace).Delete(ctx, job.Name, jb.deleteOptions())
+		if err != nil && !k8sapierror.IsNotFound(err) {
+			return fmt.Errorf("deleting job %q: %w", job.Namespace+"/"+job.Name, err)
+		}
+	}
+	return nil
+}
+
+// EstimateFootprint returns the aggregate container requests of collector jobs on the nodes,
+// cpu and memory are zero when no requests are configured
+func (jb *jobCollector) EstimateFootprint(nodeNames []string) corev1.ResourceList {
+	jb = jb.snapshot()
+	footprint := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.Quantity{},
+		corev1.ResourceMemory: resource.Quantity{},
+	}
+	jobOptions := []JobOption{
+		WithTemplate(jb.templateName),
+		WithResourceRequirements(jb.resourceRequirements),
+		WithJobSpecOverlay(jb.jobSpecOverlay),
+		WithJobStrategicMergePatch(jb.strategicMergePatch),
+	}
+	for containerName, rr := range jb.containerResourceRequirements {
+		jobOptions = append(jobOptions, WithResourceRequirementsForContainer(containerName, rr))
+	}
+	for _, mutator := range jb.podSpecMutators {
+		jobOptions = append(jobOptions, WithPodSpecMutator(mutator))
+	}
+	job, err := GetJob(jobOptions...)
+	if err != nil {
+		return footprint
+	}
+	for _, c := range job.Spec.Template.Spec.Containers {
+		for name, request := range c.Resources.Requests {
+			total := footprint[name]
+			for range nodeNames {
+				total.Add(request)
+			}
+			footprint[name] = total
+		}
+	}
+	return footprint
+}
+
+// Healthy check the collector dependencies are available: the API server is reachable
+// and the collector namespace exists
+func (jb *jobCollector) Healthy(ctx context.Context) error {
+	jb = jb.snapshot()
+	if _, err := jb.clientset.Discovery().ServerVersion(); err != nil {
+		return fmt.Errorf("getting server version: %w", err)
+	}
+	if _, err := jb.getTrivyNamespace(ctx); err != nil {
+		return fmt.Errorf("getting namespace %q: %w", jb.namespace, err)
+	}
+	return nil
+}
+
+// jobDiffFields are the job fields compared by DiffJob
+type jobDiffFields struct {
+	Labels      map[string]string
+	Annotations map[string]string
+	Spec        batchv1.JobSpec
+}
+
+// DiffJob compare the job Apply would create on the node with the live job, it returns
+// whether they differ and a human-readable diff (-live +desired). Fields unset in the
+// desired job (e.g. server defaults) are ignored
+func (jb *jobCollector) DiffJob(ctx context.Context, nodeName string) (bool, string, error) {
+	jb = jb.snapshot()
+	if jb.optionErr != nil {
+		return false, "", jb.optionErr
+	}
+	desired, err := jb.buildJob(ctx, nodeName)
+	if err != nil {
+		return false, "", err
+	}
+	live, err := jb.clientset.BatchV1().Jobs(desired.Namespace).Get(ctx, desired.Name, metav1.GetOptions{})
+	if err != nil {
+		if k8sapierror.IsNotFound(err) {
+			return true, fmt.Sprintf("job %q does not exist", desired.Namespace+"/"+desired.Name), nil
+		}
+		return false, "", fmt.Errorf("getting job: %w", err)
+	}
+	diff := cmp.Diff(
+		jobDiffFields{Labels: live.Labels, Annotations: live.Annotations, Spec: live.Spec},
+		jobDiffFields{Labels: desired.Labels, Annotations: desired.Annotations, Spec: desired.Spec},
+		ignoreUnsetDesired(),
+		cmp.Comparer(func(a, b resource.Quantity) bool { return a.Cmp(b) == 0 }),
+	)
+	return len(diff) > 0, diff, nil
+}
+
+// ignoreUnsetDesired ignore fields and map entries unset on the desired (right) side
+func ignoreUnsetDesired() cmp.Option {
+	return cmp.FilterPath(func(path cmp.Path) bool {
+		if mapIndex, ok := path.Last().(cmp.MapIndex); ok {
+			if _, desired := mapIndex.Values(); !desired.IsValid() {
+				return true
+			}
+		}
+		_, desired := path.Last().Values()
+		if !desired.IsValid() {
+			return false
+		}
+		switch desired.Kind() {
+		case reflect.Slice, reflect.Map:
+			return desired.IsNil() || desired.Len() == 0
+		case reflect.String:
+			return desired.Len() == 0
+		case reflect.Interface, reflect.Pointer:
+			return desired.IsNil()
+		}
+		return false
+	}, cmp.Ignore())
 }
@@ -0,0 +1,1984 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sapierror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+)
+
+func newTestCollector(objects []runtime.Object, opts ...CollectorOption) (*jobCollector, *fake.Clientset) {
+	clientset := fake.NewSimpleClientset(objects...)
+	jc := &jobCollector{
+		clientset:    clientset,
+		logsReader:   NewLogsReader(clientset),
+		namespace:    "trivy-temp",
+		templateName: NodeCollectorName,
+		clock:        realClock{},
+		mu:           &sync.RWMutex{},
+		lifecycle:    &collectorLifecycle{},
+	}
+	for _, opt := range opts {
+		opt(jc)
+	}
+	return jc, clientset
+}
+
+// completeJobsOnWatch set jobs condition once the runnable job informer watch jobs,
+// as there is no job controller to drive fake clientset jobs
+func completeJobsOnWatch(clientset *fake.Clientset, conditionType batchv1.JobConditionType) {
+	clientset.PrependWatchReactor("jobs", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		namespace := action.GetNamespace()
+		go func() {
+			// let the default reactor register the watcher
+			time.Sleep(50 * time.Millisecond)
+			jobList, err := clientset.BatchV1().Jobs(namespace).List(context.Background(), metav1.ListOptions{})
+			if err != nil {
+				return
+			}
+			for _, job := range jobList.Items {
+				if len(job.Status.Conditions) > 0 {
+					continue
+				}
+				job.Spec.Selector = &metav1.LabelSelector{
+					MatchLabels: map[string]string{"batch.kubernetes.io/controller-uid": job.Name},
+				}
+				job.Status.Conditions = []batchv1.JobCondition{{Type: conditionType, Status: corev1.ConditionTrue, Reason: "Test"}}
+				job.Status.StartTime = ptr.To(metav1.Now())
+				if conditionType == batchv1.JobComplete {
+					job.Status.CompletionTime = ptr.To(metav1.Now())
+				}
+				_, _ = clientset.BatchV1().Jobs(namespace).Update(context.Background(), &job, metav1.UpdateOptions{})
+			}
+		}()
+		return false, nil, nil
+	})
+}
+
+type fakePodExecutor struct {
+	stdout string
+	stderr string
+	err    error
+	// calls record executed commands as namespace/pod/container: cmd
+	calls []string
+}
+
+func (f *fakePodExecutor) Exec(_ context.Context, namespace, podName, containerName string, cmd []string, _ io.Reader, stdout, stderr io.Writer) error {
+	f.calls = append(f.calls, fmt.Sprintf("%s/%s/%s: %s", namespace, podName, containerName, strings.Join(cmd, " ")))
+	_, _ = io.WriteString(stdout, f.stdout)
+	_, _ = io.WriteString(stderr, f.stderr)
+	return f.err
+}
+
+type fakeLogsReader struct {
+	LogsReader
+	logs io.ReadCloser
+}
+
+func (f *fakeLogsReader) GetLogsByJobAndContainerName(_ context.Context, _ *batchv1.Job, _ string) (io.ReadCloser, error) {
+	return f.logs, nil
+}
+
+func TestListCollectorJobs(t *testing.T) {
+	created := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	collectorJob := func(name, nodeName string, status batchv1.JobStatus) runtime.Object {
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "trivy-temp",
+				CreationTimestamp: created,
+				Labels: map[string]string{
+					TrivyCollectorName: NodeCollectorName,
+					TrivyResourceName:  nodeName,
+					TrivyResourceKind:  "Node",
+				},
+			},
+			Status: status,
+		}
+	}
+	objects := []runtime.Object{
+		collectorJob("node-collector-1", "node-1", batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}},
+		}),
+		collectorJob("node-collector-2", "node-2", batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}},
+		}),
+		collectorJob("node-collector-3", "node-3", batchv1.JobStatus{Active: 1}),
+		collectorJob("node-collector-4", "node-4", batchv1.JobStatus{}),
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "trivy-temp"}},
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+			Name:      "other-namespace",
+			Namespace: "default",
+			Labels:    map[string]string{TrivyCollectorName: NodeCollectorName},
+		}},
+	}
+	jc, _ := newTestCollector(objects)
+
+	got, err := jc.ListCollectorJobs(context.Background())
+	assert.NoError(t, err)
+	sort.Slice(got, func(i, j int) bool { return got[i].Name < got[j].Name })
+	assert.Equal(t, []CollectorJobInfo{
+		{Name: "node-collector-1", NodeName: "node-1", Status: "Succeeded", CreationTimestamp: created.Time},
+		{Name: "node-collector-2", NodeName: "node-2", Status: "Failed", CreationTimestamp: created.Time},
+		{Name: "node-collector-3", NodeName: "node-3", Status: "Running", CreationTimestamp: created.Time},
+		{Name: "node-collector-4", NodeName: "node-4", Status: "Pending", CreationTimestamp: created.Time},
+	}, got)
+}
+
+func TestReadLogsMaxLogBytes(t *testing.T) {
+	tests := []struct {
+		name        string
+		logs        string
+		maxLogBytes int64
+		wantOutput  string
+		wantErr     error
+	}{
+		{name: "no cap", logs: "0123456789", wantOutput: "0123456789"},
+		{name: "within cap", logs: "0123456789", maxLogBytes: 10, wantOutput: "0123456789"},
+		{name: "exceeding cap", logs: "0123456789", maxLogBytes: 4, wantOutput: "0123", wantErr: ErrMaxLogBytesExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jc, _ := newTestCollector(nil, WithMaxLogBytes(tt.maxLogBytes))
+			jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(tt.logs))}
+			got, err := jc.readLogs(context.Background(), &batchv1.Job{})
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantOutput, string(got))
+		})
+	}
+}
+
+func TestReadLogsTimeout(t *testing.T) {
+	// stream which blocks until closed
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	jc, _ := newTestCollector(nil, WithLogReadTimeout(50*time.Millisecond))
+	jc.logsReader = &fakeLogsReader{logs: pr}
+
+	start := time.Now()
+	_, err := jc.readLogs(context.Background(), &batchv1.Job{})
+	assert.ErrorIs(t, err, ErrLogReadTimeout)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestApplyDeadlineFromContext(t *testing.T) {
+	tests := []struct {
+		name             string
+		opts             []CollectorOption
+		contextTimeout   time.Duration
+		wantDeadlineSecs int64
+	}{
+		{
+			name:             "template deadline when disabled",
+			contextTimeout:   100 * time.Second,
+			wantDeadlineSecs: 300,
+		},
+		{
+			name:             "deadline from context",
+			opts:             []CollectorOption{WithDeadlineFromContext(true)},
+			contextTimeout:   100 * time.Second,
+			wantDeadlineSecs: 100,
+		},
+		{
+			name:             "collector timeout shorter than context deadline",
+			opts:             []CollectorOption{WithDeadlineFromContext(true), WithCollectorTimeout(time.Minute)},
+			contextTimeout:   100 * time.Second,
+			wantDeadlineSecs: 60,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jc, _ := newTestCollector(nil, append(tt.opts, WithName("node-collector-test"))...)
+			ctx, cancel := context.WithTimeout(context.Background(), tt.contextTimeout)
+			defer cancel()
+			job, err := jc.Apply(ctx, "node-1")
+			assert.NoError(t, err)
+			if assert.NotNil(t, job.Spec.ActiveDeadlineSeconds) {
+				assert.InDelta(t, tt.wantDeadlineSecs, *job.Spec.ActiveDeadlineSeconds, 1)
+			}
+		})
+	}
+}
+
+func TestApplyQPSLimit(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	jc, clientset := newTestCollector(nil, WithQPSLimit(20, 1), WithCollectorClock(clock))
+	done := make(chan error, 1)
+	go func() {
+		for _, nodeName := range []string{"node-1", "node-2", "node-3"} {
+			jc.AppendLabels(WithName("node-collector-" + nodeName))
+			if _, err := jc.Apply(context.Background(), nodeName); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	jobCount := func() int {
+		jobList, err := clientset.BatchV1().Jobs("trivy-temp").List(context.Background(), metav1.ListOptions{})
+		assert.NoError(t, err)
+		return len(jobList.Items)
+	}
+
+	// burst of 1 at 20 qps space creates by 50ms
+	for created := 1; created < 3; created++ {
+		assert.Eventually(t, clock.hasWaiters, 5*time.Second, time.Millisecond)
+		assert.Equal(t, created, jobCount())
+		clock.Advance(49 * time.Millisecond)
+		assert.True(t, clock.hasWaiters())
+		clock.Advance(time.Millisecond)
+	}
+	assert.NoError(t, <-done)
+	assert.Equal(t, 3, jobCount())
+}
+
+func TestCleanupPods(t *testing.T) {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-collector-1", Namespace: "trivy-temp"},
+		Spec: batchv1.JobSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"batch.kubernetes.io/controller-uid": "abc"},
+			},
+		},
+	}
+	pod := func(name, uid string) runtime.Object {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "trivy-temp",
+			Labels:    map[string]string{"batch.kubernetes.io/controller-uid": uid},
+		}}
+	}
+	jc, clientset := newTestCollector([]runtime.Object{
+		job,
+		pod("node-collector-1-abc", "abc"),
+		pod("node-collector-1-def", "abc"),
+		pod("node-collector-2-abc", "xyz"),
+	})
+
+	err := jc.CleanupPods(context.Background(), job)
+	assert.NoError(t, err)
+
+	podList, err := clientset.CoreV1().Pods("trivy-temp").List(context.Background(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, podList.Items, 1)
+	assert.Equal(t, "node-collector-2-abc", podList.Items[0].Name)
+	_, err = clientset.BatchV1().Jobs("trivy-temp").Get(context.Background(), job.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+}
+
+func TestResumeJob(t *testing.T) {
+	jc, clientset := newTestCollector(nil, WithJobSuspend(true), WithName("node-collector-1"))
+	job, err := jc.Apply(context.Background(), "node-1")
+	assert.NoError(t, err)
+	assert.True(t, *job.Spec.Suspend)
+
+	err = jc.ResumeJob(context.Background(), job)
+	assert.NoError(t, err)
+	job, err = clientset.BatchV1().Jobs("trivy-temp").Get(context.Background(), "node-collector-1", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.False(t, *job.Spec.Suspend)
+}
+
+func TestApplyUnschedulableNode(t *testing.T) {
+	nodes := []runtime.Object{
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+	}
+	jc, clientset := newTestCollector(nodes, WithCheckUnschedulableNode(true))
+
+	jc.AppendLabels(WithName("node-collector-1"))
+	_, err := jc.Apply(context.Background(), "node-1")
+	assert.NoError(t, err)
+
+	jc.AppendLabels(WithName("node-collector-2"))
+	_, err = jc.Apply(context.Background(), "node-2")
+	assert.ErrorIs(t, err, ErrNodeUnschedulable)
+
+	jobList, err := clientset.BatchV1().Jobs("trivy-temp").List(context.Background(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, jobList.Items, 1)
+	assert.Equal(t, "node-collector-1", jobList.Items[0].Name)
+}
+
+func TestGetJobEvents(t *testing.T) {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-collector-1", Namespace: "trivy-temp"},
+		Spec: batchv1.JobSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"batch.kubernetes.io/controller-uid": "abc"},
+			},
+		},
+	}
+	event := func(name, kind, objectName, uid, reason string) runtime.Object {
+		return &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "trivy-temp"},
+			InvolvedObject: corev1.ObjectReference{Kind: kind, Name: objectName, Namespace: "trivy-temp", UID: types.UID(uid)},
+			Reason:         reason,
+		}
+	}
+	jc, clientset := newTestCollector([]runtime.Object{
+		job,
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      "node-collector-1-abc",
+			Namespace: "trivy-temp",
+			UID:       "pod-uid-1",
+			Labels:    map[string]string{"batch.kubernetes.io/controller-uid": "abc"},
+		}},
+		event("event-1", "Job", "node-collector-1", "job-uid-1", "SuccessfulCreate"),
+		event("event-2", "Pod", "node-collector-1-abc", "pod-uid-1", "FailedScheduling"),
+		event("event-3", "Pod", "node-collector-2-abc", "pod-uid-2", "FailedScheduling"),
+		event("event-4", "Job", "node-collector-2", "job-uid-2", "SuccessfulCreate"),
+		event("event-5", "Node", "node-collector-1", "node-uid-1", "NodeReady"),
+	})
+	// the fake clientset ignores field selectors
+	clientset.PrependReactor("list", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		restrictions := action.(k8stesting.ListAction).GetListRestrictions()
+		obj, err := clientset.Tracker().List(corev1.SchemeGroupVersion.WithResource("events"), corev1.SchemeGroupVersion.WithKind("Event"), action.GetNamespace())
+		if err != nil {
+			return true, nil, err
+		}
+		eventList := obj.(*corev1.EventList)
+		var items []corev1.Event
+		for _, e := range eventList.Items {
+			if restrictions.Fields.Matches(fields.Set{
+				"involvedObject.kind": e.InvolvedObject.Kind,
+				"involvedObject.name": e.InvolvedObject.Name,
+				"involvedObject.uid":  string(e.InvolvedObject.UID),
+			}) {
+				items = append(items, e)
+			}
+		}
+		eventList.Items = items
+		return true, eventList, nil
+	})
+
+	events, err := jc.GetJobEvents(context.Background(), job)
+	assert.NoError(t, err)
+	var reasons []string
+	for _, e := range events {
+		reasons = append(reasons, e.InvolvedObject.Kind+"/"+e.Reason)
+	}
+	assert.ElementsMatch(t, []string{"Job/SuccessfulCreate", "Pod/FailedScheduling"}, reasons)
+	var fieldSelectors []string
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() == "list" && action.GetResource().Resource == "events" {
+			fieldSelectors = append(fieldSelectors, action.(k8stesting.ListAction).GetListRestrictions().Fields.String())
+		}
+	}
+	assert.Equal(t, []string{
+		"involvedObject.kind=Job,involvedObject.name=node-collector-1",
+		"involvedObject.kind=Pod,involvedObject.uid=pod-uid-1",
+	}, fieldSelectors)
+}
+
+func TestApplyAndCollectTracing(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+	jc, clientset := newTestCollector(nil, WithTracerProvider(tp), WithNodeConfig(true))
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+
+	output, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"info":{}}`, output)
+
+	spans := spanRecorder.Ended()
+	var names []string
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+	assert.Equal(t, []string{"namespace", "rbac", "apply", "wait", "logs", "ApplyAndCollect"}, names)
+	root := spans[len(spans)-1]
+	assert.Contains(t, root.Attributes(), attribute.String("node.name", "node-1"))
+	assert.Contains(t, root.Attributes(), attribute.String("job.name", "node-collector-"+ComputeHash(ObjectRef{
+		Kind:      "Node-Info",
+		Name:      "node-1",
+		Namespace: "trivy-temp",
+	})))
+	for _, span := range spans[:len(spans)-1] {
+		assert.Equal(t, root.SpanContext().SpanID(), span.Parent().SpanID())
+	}
+}
+
+func TestApplyAndCollectTracingError(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+	jc, clientset := newTestCollector(nil, WithTracerProvider(tp))
+	completeJobsOnWatch(clientset, batchv1.JobFailed)
+
+	_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.Error(t, err)
+
+	spans := spanRecorder.Ended()
+	var names []string
+	for _, span := range spans {
+		names = append(names, span.Name())
+		if span.Name() == "wait" || span.Name() == "ApplyAndCollect" {
+			assert.Equal(t, codes.Error, span.Status().Code)
+		}
+	}
+	assert.Equal(t, []string{"namespace", "apply", "wait", "ApplyAndCollect"}, names)
+}
+
+func TestReadResultFile(t *testing.T) {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-collector-1", Namespace: "trivy-temp"},
+		Spec: batchv1.JobSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"batch.kubernetes.io/controller-uid": "abc"},
+			},
+		},
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "node-collector-1-abc",
+		Namespace: "trivy-temp",
+		Labels:    map[string]string{"batch.kubernetes.io/controller-uid": "abc"},
+	}}
+
+	executor := &fakePodExecutor{stdout: `{"info":{}}`}
+	jc, _ := newTestCollector([]runtime.Object{job, pod}, WithPodExecutor(executor), WithResultFromFile("/results/output.json"))
+	output, err := jc.ReadResultFile(context.Background(), job)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"info":{}}`, string(output))
+	assert.Equal(t, []string{"trivy-temp/node-collector-1-abc/node-collector: cat /results/output.json"}, executor.calls)
+
+	executor = &fakePodExecutor{stderr: "No such file or directory", err: errors.New("command terminated with exit code 1")}
+	jc, _ = newTestCollector([]runtime.Object{job, pod}, WithPodExecutor(executor), WithResultFromFile("/results/output.json"))
+	_, err = jc.ReadResultFile(context.Background(), job)
+	assert.ErrorContains(t, err, "No such file or directory")
+
+	// collection fail fast without pod executor
+	jc, clientset := newTestCollector(nil, WithResultFromFile("/results/output.json"))
+	_, err = jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.ErrorContains(t, err, "pod executor is not configured")
+	assert.Empty(t, clientset.Actions())
+}
+
+func TestExecInCollector(t *testing.T) {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-collector-1", Namespace: "trivy-temp"},
+		Spec: batchv1.JobSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"batch.kubernetes.io/controller-uid": "abc"},
+			},
+		},
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "node-collector-1-abc",
+		Namespace: "trivy-temp",
+		Labels:    map[string]string{"batch.kubernetes.io/controller-uid": "abc"},
+	}}
+
+	executor := &fakePodExecutor{stdout: "/etc/kubernetes", stderr: "warning"}
+	jc, _ := newTestCollector([]runtime.Object{job, pod}, WithPodExecutor(executor))
+	stdout, stderr, err := jc.ExecInCollector(context.Background(), job, NodeCollectorName, []string{"ls", "/etc"})
+	assert.NoError(t, err)
+	assert.Equal(t, "/etc/kubernetes", stdout)
+	assert.Equal(t, "warning", stderr)
+	assert.Equal(t, []string{"trivy-temp/node-collector-1-abc/node-collector: ls /etc"}, executor.calls)
+
+	executor = &fakePodExecutor{stderr: "ls: /root: Permission denied", err: errors.New("command terminated with exit code 1")}
+	jc, _ = newTestCollector([]runtime.Object{job, pod}, WithPodExecutor(executor))
+	_, stderr, err = jc.ExecInCollector(context.Background(), job, NodeCollectorName, []string{"ls", "/root"})
+	assert.ErrorContains(t, err, "exit code 1")
+	assert.Equal(t, "ls: /root: Permission denied", stderr)
+
+	jc, _ = newTestCollector([]runtime.Object{job, pod})
+	_, _, err = jc.ExecInCollector(context.Background(), job, NodeCollectorName, []string{"ls"})
+	assert.ErrorContains(t, err, "pod executor is not configured")
+}
+
+func TestNewCollectorDefaultPodExecutor(t *testing.T) {
+	config := &rest.Config{Host: "https://localhost"}
+	clientset := kubernetes.NewForConfigOrDie(config)
+
+	jc := NewCollector(&testCluster{clientset: clientset, restConfig: config}).(*jobCollector)
+	assert.Equal(t, &spdyPodExecutor{config: config, clientset: clientset}, jc.podExecutor)
+
+	// WithPodExecutor override the default executor
+	executor := &fakePodExecutor{}
+	jc = NewCollector(&testCluster{clientset: clientset, restConfig: config}, WithPodExecutor(executor)).(*jobCollector)
+	assert.Equal(t, executor, jc.podExecutor)
+}
+
+func TestApplyAndCollectOutputValidator(t *testing.T) {
+	jsonValidator := func(output []byte) error {
+		var nodeInfo map[string]interface{}
+		return json.Unmarshal(output, &nodeInfo)
+	}
+	tests := []struct {
+		name    string
+		logs    string
+		wantErr bool
+	}{
+		{name: "valid output", logs: `{"info":{}}`},
+		{name: "invalid output", logs: `panic: runtime error`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jc, clientset := newTestCollector(nil, WithOutputValidator(jsonValidator))
+			jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(tt.logs))}
+			completeJobsOnWatch(clientset, batchv1.JobComplete)
+
+			output, err := jc.ApplyAndCollect(context.Background(), "node-1")
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "validating output")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.logs, output)
+		})
+	}
+}
+
+func TestApplyAndCollectResultPostProcessor(t *testing.T) {
+	redact := func(output []byte) ([]byte, error) {
+		return bytes.ReplaceAll(output, []byte("/home/admin"), []byte("<redacted>")), nil
+	}
+	compact := func(output []byte) ([]byte, error) {
+		var buf bytes.Buffer
+		err := json.Compact(&buf, output)
+		return buf.Bytes(), err
+	}
+	jc, clientset := newTestCollector(nil, WithResultPostProcessor(redact), WithResultPostProcessor(compact))
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info": {"kubeconfig": "/home/admin/.kube/config"}}`))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+
+	output, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"info":{"kubeconfig":"<redacted>/.kube/config"}}`, output)
+
+	// post-processor error fail the collection
+	jc, clientset = newTestCollector(nil, WithResultPostProcessor(compact))
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`panic: runtime error`))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+	_, err = jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.ErrorContains(t, err, "post-processing output")
+}
+
+func TestValidateJob(t *testing.T) {
+	job, err := GetJob(WithTemplate(NodeCollectorName), WithNamespace("trivy-temp"))
+	assert.NoError(t, err)
+
+	jc, _ := newTestCollector(nil)
+	assert.NoError(t, jc.ValidateJob(context.Background(), job))
+
+	jc, clientset := newTestCollector(nil)
+	clientset.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, k8sapierror.NewForbidden(schema.GroupResource{Group: "batch", Resource: "jobs"}, job.Name,
+			errors.New(`violates PodSecurity "restricted:latest": host namespaces (hostPID=true)`))
+	})
+	err = jc.ValidateJob(context.Background(), job)
+	assert.True(t, k8sapierror.IsForbidden(err))
+	assert.ErrorContains(t, err, "violates PodSecurity")
+
+	// dry-run is not rate limited
+	jc, _ = newTestCollector(nil, WithQPSLimit(1, 1), WithCollectorClock(&fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}))
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.NoError(t, jc.throttle(ctx))
+	cancel()
+	assert.NoError(t, jc.ValidateJob(ctx, job))
+}
+
+func TestAppendLabelsConcurrentApply(t *testing.T) {
+	jc, clientset := newTestCollector(nil, WithJobLabels(map[string]string{TrivyCollectorName: NodeCollectorName}))
+	nodeNames := []string{"node-1", "node-2", "node-3", "node-4", "node-5"}
+
+	var wg sync.WaitGroup
+	for _, nodeName := range nodeNames {
+		wg.Add(2)
+		go func(nodeName string) {
+			defer wg.Done()
+			jc.AppendLabels(WithJobLabels(map[string]string{TrivyResourceName: nodeName}))
+		}(nodeName)
+		go func(nodeName string) {
+			defer wg.Done()
+			// job name is not taken from labels, use a collector copy per node
+			c := jc.snapshot()
+			c.name = "node-collector-" + nodeName
+			_, err := c.Apply(context.Background(), nodeName)
+			assert.NoError(t, err)
+		}(nodeName)
+	}
+	wg.Wait()
+
+	jobList, err := clientset.BatchV1().Jobs("trivy-temp").List(context.Background(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, jobList.Items, len(nodeNames))
+	for _, job := range jobList.Items {
+		assert.Equal(t, NodeCollectorName, job.Labels[TrivyCollectorName])
+	}
+}
+
+func TestApplyAndCollectResultCache(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	jc, clientset := newTestCollector(nil, WithResultCache(time.Minute), WithCollectorClock(clock))
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+	countJobCreates := func() int {
+		var count int
+		for _, action := range clientset.Actions() {
+			if action.Matches("create", "jobs") {
+				count++
+			}
+		}
+		return count
+	}
+
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":"first"}`))}
+	output, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"info":"first"}`, output)
+	assert.Equal(t, 1, countJobCreates())
+
+	// within ttl the cached output is returned and no job is created
+	clock.Advance(30 * time.Second)
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":"second"}`))}
+	output, err = jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"info":"first"}`, output)
+	assert.Equal(t, 1, countJobCreates())
+
+	// after ttl the job runs again
+	clock.Advance(time.Minute)
+	output, err = jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"info":"second"}`, output)
+	assert.Equal(t, 2, countJobCreates())
+}
+
+func TestApplyAndCollectNodeRemoved(t *testing.T) {
+	nodes := []runtime.Object{&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}}
+	jc, clientset := newTestCollector(nodes)
+	// node is deleted while the job runs, the pod is evicted and the job fails
+	completeJobsOnWatch(clientset, batchv1.JobFailed)
+	clientset.PrependWatchReactor("jobs", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		go func() {
+			_ = clientset.CoreV1().Nodes().Delete(context.Background(), "node-1", metav1.DeleteOptions{})
+		}()
+		return false, nil, nil
+	})
+
+	_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.ErrorIs(t, err, ErrNodeRemoved)
+
+	// node still exists, the job error is returned
+	jc, clientset = newTestCollector(nodes)
+	completeJobsOnWatch(clientset, batchv1.JobFailed)
+	_, err = jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNodeRemoved)
+}
+
+func TestApplyAdditionalImagePullSecrets(t *testing.T) {
+	jc, _ := newTestCollector(nil, WithPodAdditionalImagePullSecret("registry-a"))
+	jc.AppendLabels(WithPodAdditionalImagePullSecret("registry-b"))
+
+	job, err := jc.Apply(context.Background(), "node-1")
+	assert.NoError(t, err)
+	assert.Equal(t, []corev1.LocalObjectReference{
+		{Name: "registry-a"},
+		{Name: "registry-b"},
+	}, job.Spec.Template.Spec.ImagePullSecrets)
+}
+
+func TestApplyAdaptiveTimeout(t *testing.T) {
+	nodes := []runtime.Object{
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "small-node"},
+			Status:     corev1.NodeStatus{Capacity: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")}},
+		},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "large-node"},
+			Status:     corev1.NodeStatus{Capacity: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("110")}},
+		},
+	}
+	// one minute plus one second per schedulable pod
+	adaptiveTimeout := func(node corev1.Node) time.Duration {
+		return time.Minute + time.Duration(node.Status.Capacity.Pods().Value())*time.Second
+	}
+	jc, _ := newTestCollector(nodes, WithCollectorTimeout(5*time.Minute), WithAdaptiveTimeout(adaptiveTimeout))
+
+	tests := []struct {
+		nodeName string
+		want     int64
+	}{
+		{nodeName: "small-node", want: 70},
+		{nodeName: "large-node", want: 170},
+		// unknown node falls back to the collector timeout
+		{nodeName: "unknown-node", want: 300},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nodeName, func(t *testing.T) {
+			jc.AppendLabels(WithName("node-collector-" + tt.nodeName))
+			job, err := jc.Apply(context.Background(), tt.nodeName)
+			assert.NoError(t, err)
+			assert.Equal(t, ptr.To[int64](tt.want), job.Spec.ActiveDeadlineSeconds)
+		})
+	}
+}
+
+func TestEstimateFootprint(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []CollectorOption
+		nodeNames []string
+		want      corev1.ResourceList
+	}{
+		{
+			name: "configured requests",
+			opts: []CollectorOption{WithContainerResourceRequirements(&corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("100m"),
+					corev1.ResourceMemory: resource.MustParse("128Mi"),
+				},
+			})},
+			nodeNames: []string{"node-1", "node-2", "node-3"},
+			want: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("300m"),
+				corev1.ResourceMemory: resource.MustParse("384Mi"),
+			},
+		},
+		{
+			name:      "template requests",
+			nodeNames: []string{"node-1", "node-2"},
+			want: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("100m"),
+				corev1.ResourceMemory: resource.MustParse("100M"),
+			},
+		},
+		{
+			name:      "no requests",
+			opts:      []CollectorOption{WithContainerResourceRequirements(&corev1.ResourceRequirements{})},
+			nodeNames: []string{"node-1", "node-2"},
+			want: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.Quantity{},
+				corev1.ResourceMemory: resource.Quantity{},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jc, _ := newTestCollector(nil, tt.opts...)
+			got := jc.EstimateFootprint(tt.nodeNames)
+			assert.Len(t, got, len(tt.want))
+			for name, want := range tt.want {
+				gotQuantity := got[name]
+				assert.Equal(t, 0, want.Cmp(gotQuantity), "%s: want %s, got %s", name, want.String(), gotQuantity.String())
+			}
+		})
+	}
+}
+
+func TestApplyAndCollectServiceAccountImagePullSecrets(t *testing.T) {
+	imagePullSecrets := []corev1.LocalObjectReference{{Name: "registry-a"}}
+	jc, clientset := newTestCollector(nil, WithNodeConfig(true), WithImagePullSecretsFromServiceAccount(imagePullSecrets))
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+
+	_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.NoError(t, err)
+	var createdSA *corev1.ServiceAccount
+	for _, action := range clientset.Actions() {
+		if createAction, ok := action.(k8stesting.CreateAction); ok && action.GetResource().Resource == "serviceaccounts" {
+			createdSA = createAction.GetObject().(*corev1.ServiceAccount)
+		}
+	}
+	if assert.NotNil(t, createdSA) {
+		assert.Equal(t, imagePullSecrets, createdSA.ImagePullSecrets)
+	}
+}
+
+func TestCleanupNamespace(t *testing.T) {
+	tests := []struct {
+		name          string
+		namespace     *corev1.Namespace
+		opts          []CollectorOption
+		wantNamespace bool
+	}{
+		{
+			name:          "pre-existing namespace is kept",
+			namespace:     &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "trivy-temp"}},
+			wantNamespace: true,
+		},
+		{
+			name:      "pre-existing namespace is deleted when owned",
+			namespace: &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "trivy-temp"}},
+			opts:      []CollectorOption{WithOwnNamespace(true)},
+		},
+		{
+			name: "auto created namespace is deleted",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var objects []runtime.Object
+			if tt.namespace != nil {
+				objects = append(objects, tt.namespace)
+			}
+			jc, clientset := newTestCollector(objects, tt.opts...)
+			jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+			completeJobsOnWatch(clientset, batchv1.JobComplete)
+			_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+			assert.NoError(t, err)
+			_, err = clientset.CoreV1().Namespaces().Get(context.Background(), "trivy-temp", metav1.GetOptions{})
+			assert.NoError(t, err)
+
+			jc.Cleanup(context.Background())
+			_, err = clientset.CoreV1().Namespaces().Get(context.Background(), "trivy-temp", metav1.GetOptions{})
+			if tt.wantNamespace {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, k8sapierror.IsNotFound(err))
+		})
+	}
+}
+
+func TestCleanupAndWaitNamespace(t *testing.T) {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "trivy-temp",
+		Labels: map[string]string{TrivyAutoCreated: "true"},
+	}}
+	// namespace linger in terminating phase before it is removed
+	lingerOnDelete := func(clientset *fake.Clientset, removeAfter time.Duration) {
+		namespaces := corev1.SchemeGroupVersion.WithResource("namespaces")
+		clientset.PrependReactor("delete", "namespaces", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			terminatingNamespace := namespace.DeepCopy()
+			terminatingNamespace.Status.Phase = corev1.NamespaceTerminating
+			if err := clientset.Tracker().Update(namespaces, terminatingNamespace, ""); err != nil {
+				return true, nil, err
+			}
+			go func() {
+				time.Sleep(removeAfter)
+				_ = clientset.Tracker().Delete(namespaces, "", namespace.Name)
+			}()
+			return true, nil, nil
+		})
+	}
+
+	jc, clientset := newTestCollector([]runtime.Object{namespace})
+	jc.namespacePollInterval = 10 * time.Millisecond
+	lingerOnDelete(clientset, 100*time.Millisecond)
+	err := jc.CleanupAndWaitNamespace(context.Background(), time.Minute)
+	assert.NoError(t, err)
+	_, err = clientset.CoreV1().Namespaces().Get(context.Background(), "trivy-temp", metav1.GetOptions{})
+	assert.True(t, k8sapierror.IsNotFound(err))
+
+	// namespace is not removed within timeout
+	jc, clientset = newTestCollector([]runtime.Object{namespace})
+	jc.namespacePollInterval = 10 * time.Millisecond
+	lingerOnDelete(clientset, time.Minute)
+	err = jc.CleanupAndWaitNamespace(context.Background(), 50*time.Millisecond)
+	assert.ErrorContains(t, err, `waiting for namespace "trivy-temp" to be deleted`)
+
+	// pre-existing namespace is kept
+	jc, clientset = newTestCollector([]runtime.Object{&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "trivy-temp"}}})
+	err = jc.CleanupAndWaitNamespace(context.Background(), time.Minute)
+	assert.NoError(t, err)
+	_, err = clientset.CoreV1().Namespaces().Get(context.Background(), "trivy-temp", metav1.GetOptions{})
+	assert.NoError(t, err)
+}
+
+func TestApplyAndCollectTerminatingNamespace(t *testing.T) {
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "trivy-temp"},
+		Status:     corev1.NamespaceStatus{Phase: corev1.NamespaceTerminating},
+	}
+	jc, clientset := newTestCollector([]runtime.Object{namespace})
+	jc.namespacePollInterval = 10 * time.Millisecond
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		activeNamespace := namespace.DeepCopy()
+		activeNamespace.Status.Phase = corev1.NamespaceActive
+		_, _ = clientset.CoreV1().Namespaces().Update(context.Background(), activeNamespace, metav1.UpdateOptions{})
+	}()
+
+	output, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"info":{}}`, output)
+
+	// namespace never leave terminating phase
+	jc, _ = newTestCollector([]runtime.Object{namespace}, WithNamespaceReadyTimeout(50*time.Millisecond))
+	jc.namespacePollInterval = 10 * time.Millisecond
+	_, err = jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.ErrorContains(t, err, `waiting for namespace "trivy-temp" to be active`)
+}
+
+func TestApplyAndCollectResult(t *testing.T) {
+	jobName := fmt.Sprintf("%s-%s", NodeCollectorName, ComputeHash(ObjectRef{Kind: "Node-Info", Name: "node-1", Namespace: "trivy-temp"}))
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobName + "-abcde",
+			Namespace: "trivy-temp",
+			Labels:    map[string]string{"batch.kubernetes.io/controller-uid": jobName},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodSucceeded,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  NodeCollectorName,
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0, Reason: "Completed"}},
+			}},
+		},
+	}
+	jc, clientset := newTestCollector([]runtime.Object{pod})
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+
+	result, err := jc.ApplyAndCollectResult(context.Background(), "node-1")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"info":{}}`, result.Output)
+	assert.Equal(t, "node-1", result.NodeName)
+	assert.Equal(t, jobName, result.JobName)
+	assert.Equal(t, jobName+"-abcde", result.PodName)
+	assert.False(t, result.JobStartTime.IsZero())
+	assert.False(t, result.JobCompletionTime.IsZero())
+	assert.False(t, result.JobCompletionTime.Before(result.JobStartTime))
+	assert.Equal(t, int32(0), result.ExitCode)
+}
+
+func TestApplyAndCollectInNamespace(t *testing.T) {
+	jc, clientset := newTestCollector(nil)
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+
+	output, err := jc.ApplyAndCollectInNamespace(context.Background(), "node-1", "tenant-a")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"info":{}}`, output)
+	var jobNamespaces []string
+	for _, action := range clientset.Actions() {
+		if action.Matches("create", "jobs") {
+			jobNamespaces = append(jobNamespaces, action.GetNamespace())
+		}
+	}
+	assert.Equal(t, []string{"tenant-a"}, jobNamespaces)
+	_, err = clientset.CoreV1().Namespaces().Get(context.Background(), "tenant-a", metav1.GetOptions{})
+	assert.NoError(t, err)
+	// collector namespace is unchanged
+	assert.Equal(t, "trivy-temp", jc.namespace)
+}
+
+// garbageCollectPodsOnComplete run the job pod from its template on job create and garbage collect it
+// as soon as the job completes (e.g. a short job ttl), finalizers are honored like the API server does
+func garbageCollectPodsOnComplete(clientset *fake.Clientset) {
+	podsResource := corev1.SchemeGroupVersion.WithResource("pods")
+	clientset.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		job := action.(k8stesting.CreateAction).GetObject().(*batchv1.Job)
+		pod := &corev1.Pod{
+			ObjectMeta: *job.Spec.Template.ObjectMeta.DeepCopy(),
+			Spec:       *job.Spec.Template.Spec.DeepCopy(),
+			Status:     corev1.PodStatus{Phase: corev1.PodSucceeded},
+		}
+		pod.Name = job.Name + "-abcde"
+		pod.Namespace = job.Namespace
+		pod.Labels = map[string]string{"batch.kubernetes.io/controller-uid": job.Name}
+		return false, nil, clientset.Tracker().Create(podsResource, pod, job.Namespace)
+	})
+	clientset.PrependReactor("update", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		job := action.(k8stesting.UpdateAction).GetObject().(*batchv1.Job)
+		if len(job.Status.Conditions) == 0 || job.Status.Conditions[0].Type != batchv1.JobComplete {
+			return false, nil, nil
+		}
+		obj, err := clientset.Tracker().Get(podsResource, job.Namespace, job.Name+"-abcde")
+		if err != nil {
+			return false, nil, nil
+		}
+		pod := obj.(*corev1.Pod)
+		if len(pod.Finalizers) == 0 {
+			return false, nil, clientset.Tracker().Delete(podsResource, pod.Namespace, pod.Name)
+		}
+		pod.DeletionTimestamp = ptr.To(metav1.Now())
+		return false, nil, clientset.Tracker().Update(podsResource, pod, pod.Namespace)
+	})
+}
+
+func TestApplyAndCollectPodManagementFinalizer(t *testing.T) {
+	logsReaderOptions := WithLogsReaderOptions(WithPodReadyTimeout(100*time.Millisecond), WithPodPollInterval(10*time.Millisecond))
+	jobName := fmt.Sprintf("%s-%s", NodeCollectorName, ComputeHash(ObjectRef{Kind: "Node-Info", Name: "node-1", Namespace: "trivy-temp"}))
+	objects := []runtime.Object{&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}}
+
+	// pod is garbage collected before its logs are read
+	jc, clientset := newTestCollector(objects, logsReaderOptions)
+	garbageCollectPodsOnComplete(clientset)
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+	_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.ErrorIs(t, err, podControlledByJobNotFoundErr)
+
+	jc, clientset = newTestCollector(objects, logsReaderOptions, WithPodManagementFinalizer(true))
+	garbageCollectPodsOnComplete(clientset)
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+	output, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "fake logs", output)
+	// finalizer is removed once logs are read, the pod deletion can complete
+	pod, err := clientset.CoreV1().Pods("trivy-temp").Get(context.Background(), jobName+"-abcde", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.NotNil(t, pod.DeletionTimestamp)
+	assert.Empty(t, pod.Finalizers)
+}
+
+func TestApplyJobTTLSecondsAfterFinished(t *testing.T) {
+	jc, _ := newTestCollector(nil, WithJobTTLSecondsAfterFinished(30))
+	job, err := jc.Apply(context.Background(), "node-1")
+	assert.NoError(t, err)
+	assert.Equal(t, ptr.To[int32](30), job.Spec.TTLSecondsAfterFinished)
+}
+
+func TestHealthy(t *testing.T) {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "trivy-temp"}}
+	tests := []struct {
+		name        string
+		objects     []runtime.Object
+		unreachable bool
+		wantErr     string
+	}{
+		{name: "healthy", objects: []runtime.Object{namespace}},
+		{name: "api server unreachable", objects: []runtime.Object{namespace}, unreachable: true, wantErr: "getting server version"},
+		{name: "namespace missing", wantErr: `getting namespace "trivy-temp"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jc, clientset := newTestCollector(tt.objects)
+			if tt.unreachable {
+				clientset.PrependReactor("get", "version", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("connection refused")
+				})
+			}
+			err := jc.Healthy(context.Background())
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestApplyArgsTemplate(t *testing.T) {
+	nodes := []runtime.Object{
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:   "node-1",
+			Labels: map[string]string{"topology.kubernetes.io/region": "eu-west-1"},
+		}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}},
+	}
+	argsTemplate := `--region={{ index .Labels "topology.kubernetes.io/region" }} --zone={{ index .Annotations "zone" }} --node={{ .Name }}`
+	jc, _ := newTestCollector(nodes, WithArgsTemplate(argsTemplate))
+
+	tests := []struct {
+		nodeName string
+		wantArgs []string
+		wantErr  string
+	}{
+		{nodeName: "node-1", wantArgs: []string{"k8s", "--region=eu-west-1", "--zone=", "--node=node-1"}},
+		// missing labels render empty
+		{nodeName: "node-2", wantArgs: []string{"k8s", "--region=", "--zone=", "--node=node-2"}},
+		{nodeName: "node-3", wantErr: `getting node "node-3"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nodeName, func(t *testing.T) {
+			jc.AppendLabels(WithName("node-collector-" + tt.nodeName))
+			job, err := jc.Apply(context.Background(), tt.nodeName)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantArgs, job.Spec.Template.Spec.Containers[0].Args)
+		})
+	}
+
+	jc, _ = newTestCollector(nodes, WithArgsTemplate(`--region={{ .Labels`))
+	_, err := jc.Apply(context.Background(), "node-1")
+	assert.ErrorContains(t, err, "parsing args template")
+}
+
+func TestDiffJob(t *testing.T) {
+	jc, clientset := newTestCollector(nil, WithName("node-collector-1"), WithImageRef("ghcr.io/aquasecurity/node-collector:0.2.0"))
+
+	differ, diff, err := jc.DiffJob(context.Background(), "node-1")
+	assert.NoError(t, err)
+	assert.True(t, differ)
+	assert.Equal(t, `job "trivy-temp/node-collector-1" does not exist`, diff)
+
+	job, err := jc.Apply(context.Background(), "node-1")
+	assert.NoError(t, err)
+	// server defaults are ignored
+	job.Spec.Template.Spec.RestartPolicy = corev1.RestartPolicyNever
+	job.Spec.Template.Spec.SchedulerName = corev1.DefaultSchedulerName
+	job, err = clientset.BatchV1().Jobs("trivy-temp").Update(context.Background(), job, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	differ, diff, err = jc.DiffJob(context.Background(), "node-1")
+	assert.NoError(t, err)
+	assert.False(t, differ, diff)
+	assert.Empty(t, diff)
+
+	job.Spec.Template.Spec.Containers[0].Image = "ghcr.io/aquasecurity/node-collector:0.1.1"
+	_, err = clientset.BatchV1().Jobs("trivy-temp").Update(context.Background(), job, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	differ, diff, err = jc.DiffJob(context.Background(), "node-1")
+	assert.NoError(t, err)
+	assert.True(t, differ)
+	// cmp output is not stable, only check the image versions are reported
+	assert.Contains(t, diff, "Image")
+	assert.Contains(t, diff, "1.1")
+	assert.Contains(t, diff, "2.0")
+}
+
+func TestApplyWithNodeInformer(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:   "node-1",
+		Labels: map[string]string{"topology.kubernetes.io/region": "eu-west-1"},
+	}}
+	informerFactory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	nodeInformer := informerFactory.Core().V1().Nodes()
+	assert.NoError(t, nodeInformer.Informer().GetIndexer().Add(node))
+	jc, clientset := newTestCollector(nil,
+		WithNodeInformer(nodeInformer),
+		WithCheckUnschedulableNode(true),
+		WithArgsTemplate(`--region={{ index .Labels "topology.kubernetes.io/region" }}`),
+	)
+
+	job, err := jc.Apply(context.Background(), "node-1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"k8s", "--region=eu-west-1"}, job.Spec.Template.Spec.Containers[0].Args)
+	// nodes are read from the cache
+	for _, action := range clientset.Actions() {
+		assert.NotEqual(t, "nodes", action.GetResource().Resource)
+	}
+
+	// node missing from the cache is fetched
+	_, err = jc.Apply(context.Background(), "node-2")
+	assert.True(t, k8sapierror.IsNotFound(err))
+}
+
+func TestApplyAndCollectBeforeCleanup(t *testing.T) {
+	var hookJob *batchv1.Job
+	var hookErr error
+	var clientset *fake.Clientset
+	hook := func(ctx context.Context, job *batchv1.Job) {
+		hookJob = job
+		// job is not deleted yet
+		_, hookErr = clientset.BatchV1().Jobs(job.Namespace).Get(ctx, job.Name, metav1.GetOptions{})
+	}
+	var jc *jobCollector
+	jc, clientset = newTestCollector(nil, WithBeforeCleanup(hook))
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+
+	_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.NoError(t, err)
+	if assert.NotNil(t, hookJob) {
+		assert.Equal(t, "trivy-temp", hookJob.Namespace)
+		assert.NoError(t, hookErr)
+		_, err = clientset.BatchV1().Jobs("trivy-temp").Get(context.Background(), hookJob.Name, metav1.GetOptions{})
+		assert.True(t, k8sapierror.IsNotFound(err))
+	}
+}
+
+func TestClose(t *testing.T) {
+	// no collector timeout, the collection would wait forever
+	jc, clientset := newTestCollector([]runtime.Object{
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+	})
+	// job never completes, collection is aborted by Close
+	jobsWatcher := watch.NewRaceFreeFake()
+	started := make(chan struct{})
+	clientset.PrependWatchReactor("jobs", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		close(started)
+		return true, jobsWatcher, nil
+	})
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+		errCh <- err
+	}()
+	<-started
+	assert.NoError(t, jc.Close())
+	select {
+	case err := <-errCh:
+		assert.ErrorIs(t, err, ErrCollectorClosed)
+	case <-time.After(5 * time.Second):
+		t.Fatal("collection was not aborted by Close")
+	}
+	// job informer is stopped
+	assert.Eventually(t, jobsWatcher.IsStopped, 5*time.Second, 10*time.Millisecond)
+	// closing twice is fine
+	assert.NoError(t, jc.Close())
+
+	_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.ErrorIs(t, err, ErrCollectorClosed)
+	_, err = jc.Apply(context.Background(), "node-1")
+	assert.ErrorIs(t, err, ErrCollectorClosed)
+}
+
+func TestApplyAndCollectGracefulBatchShutdown(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	cancelOnWatch := func(clientset *fake.Clientset, cancel context.CancelFunc) {
+		clientset.PrependWatchReactor("jobs", func(action k8stesting.Action) (bool, watch.Interface, error) {
+			cancel()
+			return false, nil, nil
+		})
+	}
+
+	// job completing within the drain timeout is collected
+	jc, clientset := newTestCollector([]runtime.Object{node}, WithGracefulBatchShutdown(5*time.Second))
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelOnWatch(clientset, cancel)
+	output, err := jc.ApplyAndCollect(ctx, "node-1")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"info":{}}`, output)
+
+	// job still running after the drain timeout is deleted
+	jc, clientset = newTestCollector([]runtime.Object{node}, WithGracefulBatchShutdown(50*time.Millisecond))
+	ctx, cancel = context.WithCancel(context.Background())
+	cancelOnWatch(clientset, cancel)
+	_, err = jc.ApplyAndCollect(ctx, "node-1")
+	assert.Error(t, err)
+	jobs, err := clientset.BatchV1().Jobs("trivy-temp").List(context.Background(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, jobs.Items)
+	var deleted bool
+	for _, action := range clientset.Actions() {
+		deleted = deleted || (action.GetVerb() == "delete" && action.GetResource().Resource == "jobs")
+	}
+	assert.True(t, deleted)
+}
+
+func TestApplyAndCollectGracefulBatchShutdownDeadlineFromContext(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	jc, clientset := newTestCollector([]runtime.Object{node}, WithDeadlineFromContext(true), WithGracefulBatchShutdown(5*time.Second))
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	_, err := jc.ApplyAndCollect(ctx, "node-1")
+	assert.NoError(t, err)
+	var job *batchv1.Job
+	for _, action := range clientset.Actions() {
+		if createAction, ok := action.(k8stesting.CreateAction); ok && action.GetResource().Resource == "jobs" {
+			job = createAction.GetObject().(*batchv1.Job)
+		}
+	}
+	// context deadline extended by the drain timeout
+	if assert.NotNil(t, job) && assert.NotNil(t, job.Spec.ActiveDeadlineSeconds) {
+		assert.InDelta(t, 105, *job.Spec.ActiveDeadlineSeconds, 1)
+	}
+}
+
+func TestDrainContext(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	ctx, cancel := context.WithCancel(context.Background())
+	drainCtx, drainCancel := drainContext(ctx, clock, 5*time.Second)
+	defer drainCancel()
+
+	cancel()
+	assert.Eventually(t, clock.hasWaiters, time.Second, time.Millisecond)
+	clock.Advance(4 * time.Second)
+	assert.NoError(t, drainCtx.Err())
+	// cancelled once the drain timeout elapsed
+	clock.Advance(time.Second)
+	assert.Eventually(t, func() bool { return drainCtx.Err() != nil }, time.Second, time.Millisecond)
+}
+
+func TestApplyAndCollectOnJobFailed(t *testing.T) {
+	nodes := []runtime.Object{&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}}
+	var failedJob *batchv1.Job
+	var failedReason string
+	jc, clientset := newTestCollector(nodes, WithOnJobFailed(func(job *batchv1.Job, reason string) {
+		failedJob = job
+		failedReason = reason
+	}))
+	completeJobsOnWatch(clientset, batchv1.JobFailed)
+
+	_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.ErrorContains(t, err, "job failed")
+	if assert.NotNil(t, failedJob) {
+		assert.Equal(t, "trivy-temp", failedJob.Namespace)
+		assert.Equal(t, "Test", failedReason)
+	}
+}
+
+func TestApplyAndCollectVersionCompatibilityCheck(t *testing.T) {
+	jc, clientset := newTestCollector(nil,
+		WithImageRef("ghcr.io/aquasecurity/node-collector:0.0.5"),
+		WithVersionCompatibilityCheck(true),
+	)
+	_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.ErrorIs(t, err, ErrIncompatibleNodeCollector)
+	// job is not created
+	for _, action := range clientset.Actions() {
+		assert.NotEqual(t, "jobs", action.GetResource().Resource)
+	}
+
+	// unversioned image can't be checked
+	jc, clientset = newTestCollector(nil,
+		WithImageRef("ghcr.io/aquasecurity/node-collector:latest"),
+		WithVersionCompatibilityCheck(true),
+	)
+	_, err = jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.ErrorIs(t, err, ErrUnknownNodeCollectorVersion)
+	for _, action := range clientset.Actions() {
+		assert.NotEqual(t, "jobs", action.GetResource().Resource)
+	}
+
+	// template image is supported
+	jc, clientset = newTestCollector(nil, WithVersionCompatibilityCheck(true))
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+	_, err = jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.NoError(t, err)
+}
+
+func TestApplyAndCollectSchedulingTimeout(t *testing.T) {
+	nodes := []runtime.Object{&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}}
+	// pod is never scheduled
+	jc, clientset := newTestCollector(nodes, WithTimetout(time.Minute), WithSchedulingTimeout(100*time.Millisecond))
+	jc.schedulingPollInterval = 10 * time.Millisecond
+	jobsWatcher := watch.NewRaceFreeFake()
+	clientset.PrependWatchReactor("jobs", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		return true, jobsWatcher, nil
+	})
+	_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.ErrorIs(t, err, ErrSchedulingTimeout)
+	// job watch is stopped
+	assert.True(t, jobsWatcher.IsStopped())
+	// job is deleted
+	jobs, err := clientset.BatchV1().Jobs("trivy-temp").List(context.Background(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, jobs.Items)
+
+	// job completing within the scheduling timeout is collected
+	jc, clientset = newTestCollector(nodes, WithSchedulingTimeout(5*time.Second))
+	jc.schedulingPollInterval = 10 * time.Millisecond
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+	output, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"info":{}}`, output)
+}
+
+func TestOutputChecksum(t *testing.T) {
+	checksum := outputChecksum([]byte(`{"info":{"kubeletAnonymousAuthArgumentSet":{"values":[false]}},"type":"worker"}`))
+	// identical output yields identical checksums, whatever JSON formatting and key order
+	assert.Equal(t, checksum, outputChecksum([]byte(`{"info":{"kubeletAnonymousAuthArgumentSet":{"values":[false]}},"type":"worker"}`)))
+	assert.Equal(t, checksum, outputChecksum([]byte(`{
+  "type": "worker",
+  "info": {"kubeletAnonymousAuthArgumentSet": {"values": [false]}}
+}
+`)))
+	assert.NotEqual(t, checksum, outputChecksum([]byte(`{"info":{"kubeletAnonymousAuthArgumentSet":{"values":[true]}},"type":"worker"}`)))
+	// non JSON output is hashed as is
+	assert.Equal(t, outputChecksum([]byte("panic: runtime error")), outputChecksum([]byte("panic: runtime error\n")))
+	for _, trailing := range []string{`}`, `]`, `{"type":"worker"}`} {
+		output := `{"type":"worker"}` + trailing
+		sum := sha256.Sum256([]byte(output))
+		assert.Equal(t, hex.EncodeToString(sum[:]), outputChecksum([]byte(output)), output)
+	}
+	assert.Len(t, checksum, 64)
+}
+
+func TestApplyAndCollectResultChecksum(t *testing.T) {
+	checksums := make([]string, 0, 2)
+	for i := 0; i < 2; i++ {
+		jc, clientset := newTestCollector(nil)
+		jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+		completeJobsOnWatch(clientset, batchv1.JobComplete)
+		result, err := jc.ApplyAndCollectResult(context.Background(), "node-1")
+		assert.NoError(t, err)
+		checksums = append(checksums, result.Checksum)
+	}
+	assert.NotEmpty(t, checksums[0])
+	assert.Equal(t, checksums[0], checksums[1])
+}
+
+func TestApplyNodeSelectorPreferNoScheduleTaint(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Spec: corev1.NodeSpec{
+			Taints: []corev1.Taint{{Key: "dedicated", Value: "batch", Effect: corev1.TaintEffectPreferNoSchedule}},
+		},
+	}
+	// affinity prefer other nodes and the overlay select another node
+	affinity := &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{{
+			Weight: 100,
+			Preference: corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{{
+				Key: corev1.LabelHostname, Operator: corev1.NodeSelectorOpIn, Values: []string{"node-2"},
+			}}},
+		}},
+	}}
+	jc, _ := newTestCollector([]runtime.Object{node},
+		WithCheckUnschedulableNode(true),
+		WithUseNodeSelector(true),
+		WithJobNodeArch("amd64"),
+		WithJobAffinity(affinity),
+		WithCollectorJobSpecOverlay([]byte(`{"template":{"spec":{"nodeSelector":{"kubernetes.io/hostname":"node-2"}}}}`)),
+	)
+	job, err := jc.Apply(context.Background(), "node-1")
+	assert.NoError(t, err)
+	// pod is hard-pinned to the node
+	assert.Equal(t, map[string]string{
+		corev1.LabelHostname:   "node-1",
+		corev1.LabelArchStable: "amd64",
+	}, job.Spec.Template.Spec.NodeSelector)
+}
+
+func TestApplyAndCollectStartupArgs(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	jc, clientset := newTestCollector(nil,
+		WithResultCache(time.Minute),
+		WithCollectorClock(clock),
+		WithStartupArgs([]string{"--init-cache"}),
+	)
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+	collect := func() []string {
+		clientset.ClearActions()
+		jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+		_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+		assert.NoError(t, err)
+		for _, action := range clientset.Actions() {
+			if createAction, ok := action.(k8stesting.CreateAction); ok && action.GetResource().Resource == "jobs" {
+				return createAction.GetObject().(*batchv1.Job).Spec.Template.Spec.Containers[0].Args
+			}
+		}
+		return nil
+	}
+
+	// first collection
+	assert.Equal(t, []string{"k8s", "--init-cache"}, collect())
+	// cached result expired, the node was collected before
+	clock.Advance(2 * time.Minute)
+	assert.Equal(t, []string{"k8s"}, collect())
+}
+
+func TestApplyAndCollectNodeLabelsToCopy(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{
+		"topology.kubernetes.io/zone":      "eu-west-1a",
+		"node.kubernetes.io/instance-type": "m5.large",
+		"kubernetes.io/os":                 "linux",
+	}}}
+	jc, clientset := newTestCollector([]runtime.Object{node},
+		WithJobLabels(map[string]string{"app": "node-collector"}),
+		WithNodeLabelsToCopy([]string{"topology.kubernetes.io/zone", "node.kubernetes.io/instance-type", "missing"}),
+	)
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+
+	_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.NoError(t, err)
+	var labels map[string]string
+	for _, action := range clientset.Actions() {
+		if createAction, ok := action.(k8stesting.CreateAction); ok && action.GetResource().Resource == "jobs" {
+			labels = createAction.GetObject().(*batchv1.Job).Labels
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"app":                              "node-collector",
+		"topology.kubernetes.io/zone":      "eu-west-1a",
+		"node.kubernetes.io/instance-type": "m5.large",
+	}, labels)
+}
+
+type requestIDKey struct{}
+
+func TestApplyAndCollectContextValuesPropagation(t *testing.T) {
+	jc, clientset := newTestCollector(nil,
+		WithJobAnnotation(map[string]string{"owner": "trivy"}),
+		WithContextValuesPropagation("trivy.dev/request-id", requestIDKey{}, func(value any) string {
+			return "req-" + value.(string)
+		}),
+		WithContextValuesPropagation("trivy.dev/trace-id", "trace-id", nil),
+	)
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "1234")
+	_, err := jc.ApplyAndCollect(ctx, "node-1")
+	assert.NoError(t, err)
+	var annotations map[string]string
+	for _, action := range clientset.Actions() {
+		if createAction, ok := action.(k8stesting.CreateAction); ok && action.GetResource().Resource == "jobs" {
+			annotations = createAction.GetObject().(*batchv1.Job).Annotations
+		}
+	}
+	// trace id is not in the context
+	assert.Equal(t, map[string]string{
+		"owner":                "trivy",
+		"trivy.dev/request-id": "req-1234",
+	}, annotations)
+}
+
+func TestApplyAndCollectEvictionRetries(t *testing.T) {
+	var failedReason string
+	jc, clientset := newTestCollector(nil, WithEvictionRetries(1), WithOnJobFailed(func(job *batchv1.Job, reason string) {
+		failedReason = reason
+	}), WithDeletePropagationPolicy(metav1.DeletePropagationForeground))
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+	var creates int
+	clientset.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		creates++
+		job := action.(k8stesting.CreateAction).GetObject().(*batchv1.Job)
+		job.UID = types.UID(fmt.Sprintf("uid-%d", creates))
+		job.Spec.Selector = &metav1.LabelSelector{
+			MatchLabels: map[string]string{"batch.kubernetes.io/controller-uid": string(job.UID)},
+		}
+		evict := creates == 1
+		go func(job batchv1.Job) {
+			// let the informers see the job
+			time.Sleep(50 * time.Millisecond)
+			if evict {
+				pod := &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            job.Name + "-abcde",
+						Namespace:       job.Namespace,
+						Labels:          job.Spec.Selector.MatchLabels,
+						OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(&job, batchv1.SchemeGroupVersion.WithKind("Job"))},
+					},
+					Status: corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Evicted"},
+				}
+				_, _ = clientset.CoreV1().Pods(job.Namespace).Create(context.Background(), pod, metav1.CreateOptions{})
+				// with backoffLimit 0 the job fails as soon as its pod is evicted
+				job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"}}
+				_, _ = clientset.BatchV1().Jobs(job.Namespace).Update(context.Background(), &job, metav1.UpdateOptions{})
+				_, _ = clientset.CoreV1().Events(job.Namespace).Create(context.Background(), &corev1.Event{
+					ObjectMeta:     metav1.ObjectMeta{Name: job.Name + ".backoff", Namespace: job.Namespace},
+					InvolvedObject: corev1.ObjectReference{Kind: "Job", Name: job.Name, Namespace: job.Namespace, UID: job.UID},
+					Type:           corev1.EventTypeWarning,
+					Reason:         "BackoffLimitExceeded",
+				}, metav1.CreateOptions{})
+				return
+			}
+			job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+			_, _ = clientset.BatchV1().Jobs(job.Namespace).Update(context.Background(), &job, metav1.UpdateOptions{})
+		}(*job.DeepCopy())
+		return false, nil, nil
+	})
+
+	output, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"info":{}}`, output)
+	// evicted job was deleted with the collector delete options and created again
+	var verbs, podSelectors []string
+	for _, action := range clientset.Actions() {
+		if action.GetResource().Resource == "jobs" && (action.GetVerb() == "create" || action.GetVerb() == "delete") {
+			verbs = append(verbs, action.GetVerb())
+		}
+		if deleteAction, ok := action.(k8stesting.DeleteAction); ok {
+			assert.Equal(t, metav1.DeletePropagationForeground, *deleteAction.GetDeleteOptions().PropagationPolicy)
+		}
+		if listAction, ok := action.(k8stesting.ListAction); ok && action.GetResource().Resource == "pods" {
+			podSelectors = append(podSelectors, listAction.GetListRestrictions().Labels.String())
+		}
+	}
+	assert.Equal(t, []string{"create", "delete", "create", "delete"}, verbs)
+	// evicted pods are listed by the job selector
+	assert.Contains(t, podSelectors, "batch.kubernetes.io/controller-uid=uid-1")
+	// evicted job failure is ignored
+	assert.Empty(t, failedReason)
+}
+
+func TestDeletePropagationPolicy(t *testing.T) {
+	jc, clientset := newTestCollector(nil,
+		WithDeletePropagationPolicy(metav1.DeletePropagationForeground),
+		WithNodeConfig(true),
+		WithOwnNamespace(true),
+	)
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+
+	_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.NoError(t, err)
+	jc.Cleanup(context.Background())
+	policies := make(map[string]metav1.DeletionPropagation)
+	for _, action := range clientset.Actions() {
+		if deleteAction, ok := action.(k8stesting.DeleteAction); ok {
+			policies[action.GetResource().Resource] = *deleteAction.GetDeleteOptions().PropagationPolicy
+		}
+	}
+	assert.Equal(t, map[string]metav1.DeletionPropagation{
+		"jobs":                metav1.DeletePropagationForeground,
+		"clusterrolebindings": metav1.DeletePropagationForeground,
+		"clusterroles":        metav1.DeletePropagationForeground,
+		"serviceaccounts":     metav1.DeletePropagationForeground,
+		"namespaces":          metav1.DeletePropagationForeground,
+	}, policies)
+}
+
+func TestDeleteGracePeriod(t *testing.T) {
+	jc, clientset := newTestCollector(nil,
+		WithDeleteGracePeriod(ptr.To[int64](0)),
+		WithStdinConfig([]byte("checks: []")),
+		WithOwnNamespace(true),
+	)
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+
+	_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.NoError(t, err)
+	jc.Cleanup(context.Background())
+	gracePeriods := make(map[string]*int64)
+	for _, action := range clientset.Actions() {
+		if deleteAction, ok := action.(k8stesting.DeleteAction); ok {
+			gracePeriods[action.GetResource().Resource] = deleteAction.GetDeleteOptions().GracePeriodSeconds
+		}
+	}
+	assert.Equal(t, map[string]*int64{
+		"jobs":       ptr.To[int64](0),
+		"configmaps": ptr.To[int64](0),
+		"namespaces": ptr.To[int64](0),
+	}, gracePeriods)
+}
+
+func TestApplyAndCollectResultTimestamps(t *testing.T) {
+	jc, clientset := newTestCollector(nil)
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+
+	before := time.Now()
+	result, err := jc.ApplyAndCollectResult(context.Background(), "node-1")
+	assert.NoError(t, err)
+	after := time.Now()
+	assert.False(t, result.StartedAt.Before(before))
+	assert.True(t, result.FinishedAt.After(result.StartedAt))
+	assert.False(t, result.FinishedAt.After(after))
+}
+
+func TestApplyConcurrent(t *testing.T) {
+	// spare capacity, appending to the options would share the backing array between jobs
+	tolerations := make([]corev1.Toleration, 1, 4)
+	tolerations[0] = corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpExists}
+	imagePullSecrets := make([]corev1.LocalObjectReference, 1, 4)
+	imagePullSecrets[0] = corev1.LocalObjectReference{Name: "registry"}
+	jc, clientset := newTestCollector(nil,
+		WithJobTolerations(tolerations),
+		WithJobTolerateAll(),
+		WithUseNodeSelector(true),
+		WithPodImagePullSecrets(imagePullSecrets),
+		WithPodAdditionalImagePullSecret("mirror"),
+		WithContainerResourceRequirements(&corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10m")},
+		}),
+		WithJobPodSpecMutator(func(podSpec *corev1.PodSpec) {
+			podSpec.Containers[0].Resources.Requests[corev1.ResourceMemory] = resource.MustParse("100M")
+		}),
+	)
+	// return created jobs without storing them, job names are the same for all nodes
+	clientset.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, action.(k8stesting.CreateAction).GetObject(), nil
+	})
+
+	var wg sync.WaitGroup
+	jobs := make([]*batchv1.Job, 10)
+	for i := range jobs {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			job, err := jc.Apply(context.Background(), fmt.Sprintf("node-%d", i))
+			assert.NoError(t, err)
+			jobs[i] = job
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			jc.AppendLabels(WithJobLabels(map[string]string{fmt.Sprintf("label-%d", i): "value"}))
+		}(i)
+	}
+	wg.Wait()
+
+	for i, job := range jobs {
+		if !assert.NotNil(t, job) {
+			continue
+		}
+		podSpec := job.Spec.Template.Spec
+		assert.Equal(t, fmt.Sprintf("node-%d", i), podSpec.NodeSelector[corev1.LabelHostname])
+		assert.Equal(t, []corev1.Toleration{
+			{Key: "dedicated", Operator: corev1.TolerationOpExists},
+			{Operator: corev1.TolerationOpExists},
+		}, podSpec.Tolerations)
+		assert.Equal(t, []corev1.LocalObjectReference{{Name: "registry"}, {Name: "mirror"}}, podSpec.ImagePullSecrets)
+	}
+	// options are not modified
+	assert.Len(t, tolerations, 1)
+	assert.Len(t, imagePullSecrets, 1)
+	assert.Equal(t, corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10m")}, jc.resourceRequirements.Requests)
+	assert.Len(t, jc.labels, 10)
+}
+
+func TestApplyAndCollectNonZeroExitResults(t *testing.T) {
+	jobName := fmt.Sprintf("%s-%s", NodeCollectorName, ComputeHash(ObjectRef{Kind: "Node-Info", Name: "node-1", Namespace: "trivy-temp"}))
+	objects := []runtime.Object{
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      jobName + "-abcde",
+				Namespace: "trivy-temp",
+				Labels:    map[string]string{"batch.kubernetes.io/controller-uid": jobName},
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodFailed,
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:  NodeCollectorName,
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 2, Reason: "Error"}},
+				}},
+			},
+		},
+	}
+	logs := `{"info":{"kubeletConfFilePermissions":{"values":[600]}}}`
+
+	// collection fails by default
+	jc, clientset := newTestCollector(objects)
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(logs))}
+	completeJobsOnWatch(clientset, batchv1.JobFailed)
+	_, err := jc.ApplyAndCollectResult(context.Background(), "node-1")
+	assert.ErrorContains(t, err, "job failed")
+
+	jc, clientset = newTestCollector(objects, WithNonZeroExitResults(true))
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(logs))}
+	completeJobsOnWatch(clientset, batchv1.JobFailed)
+	result, err := jc.ApplyAndCollectResult(context.Background(), "node-1")
+	assert.NoError(t, err)
+	assert.Equal(t, logs, result.Output)
+	assert.Equal(t, int32(2), result.ExitCode)
+	// job is cleaned up
+	_, err = clientset.BatchV1().Jobs("trivy-temp").Get(context.Background(), jobName, metav1.GetOptions{})
+	assert.True(t, k8sapierror.IsNotFound(err))
+
+	// job pods can be found while the job is running
+	selectPods := func(clientset *fake.Clientset) {
+		clientset.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			job := action.(k8stesting.CreateAction).GetObject().(*batchv1.Job)
+			job.Spec.Selector = &metav1.LabelSelector{
+				MatchLabels: map[string]string{"batch.kubernetes.io/controller-uid": job.Name},
+			}
+			return false, nil, nil
+		})
+	}
+
+	// timeout is not a degraded collection
+	jc, clientset = newTestCollector(objects, WithNonZeroExitResults(true), WithTimetout(100*time.Millisecond))
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(logs))}
+	selectPods(clientset)
+	_, err = jc.ApplyAndCollectResult(context.Background(), "node-1")
+	assert.ErrorIs(t, err, ErrTimeout)
+
+	// nor is cancellation
+	jc, clientset = newTestCollector(objects, WithNonZeroExitResults(true))
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(logs))}
+	selectPods(clientset)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = jc.ApplyAndCollectResult(ctx, "node-1")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestCleanupJobs(t *testing.T) {
+	collectorJob := func(name string, jobLabels map[string]string) *batchv1.Job {
+		return &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "trivy-temp", Labels: jobLabels}}
+	}
+	objects := []runtime.Object{
+		collectorJob("node-collector-a", map[string]string{TrivyCollectorName: NodeCollectorName, "app.kubernetes.io/instance": "trivy-a"}),
+		collectorJob("node-collector-b", map[string]string{TrivyCollectorName: NodeCollectorName, "app.kubernetes.io/instance": "trivy-b"}),
+		collectorJob("other-job", map[string]string{"app.kubernetes.io/instance": "trivy-a"}),
+	}
+	listJobs := func(t *testing.T, clientset *fake.Clientset) []string {
+		jobList, err := clientset.BatchV1().Jobs("trivy-temp").List(context.Background(), metav1.ListOptions{})
+		assert.NoError(t, err)
+		var names []string
+		for _, job := range jobList.Items {
+			names = append(names, job.Name)
+		}
+		return names
+	}
+
+	t.Run("instance selector", func(t *testing.T) {
+		jc, clientset := newTestCollector(objects)
+		err := jc.CleanupJobs(context.Background(), labels.SelectorFromSet(labels.Set{"app.kubernetes.io/instance": "trivy-a"}))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"node-collector-b", "other-job"}, listJobs(t, clientset))
+	})
+	t.Run("all collector jobs", func(t *testing.T) {
+		jc, clientset := newTestCollector(objects)
+		err := jc.CleanupJobs(context.Background(), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"other-job"}, listJobs(t, clientset))
+	})
+}
+
+func TestApplyAndCollectNodeAnnotationsToCopy(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Annotations: map[string]string{
+		"cluster.x-k8s.io/machine":                               "worker-abcde",
+		"node.alpha.kubernetes.io/ttl":                           "0",
+		"volumes.kubernetes.io/controller-managed-attach-detach": "true",
+	}}}
+	jc, clientset := newTestCollector([]runtime.Object{node},
+		WithJobAnnotation(map[string]string{"owner": "trivy"}),
+		WithNodeAnnotationsToCopy([]string{"cluster.x-k8s.io/machine", "node.alpha.kubernetes.io/ttl", "missing"}),
+	)
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+
+	_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.NoError(t, err)
+	var annotations map[string]string
+	for _, action := range clientset.Actions() {
+		if createAction, ok := action.(k8stesting.CreateAction); ok && action.GetResource().Resource == "jobs" {
+			annotations = createAction.GetObject().(*batchv1.Job).Annotations
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"owner":                        "trivy",
+		"cluster.x-k8s.io/machine":     "worker-abcde",
+		"node.alpha.kubernetes.io/ttl": "0",
+	}, annotations)
+}
+
+func TestApplyAndCollectManagedAuth(t *testing.T) {
+	rbacActions := func(managedAuth bool) ([]string, *batchv1.Job) {
+		jc, clientset := newTestCollector(nil, WithNodeConfig(true), WithManagedAuth(managedAuth))
+		jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+		completeJobsOnWatch(clientset, batchv1.JobComplete)
+		_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+		assert.NoError(t, err)
+		var actions []string
+		var job *batchv1.Job
+		for _, action := range clientset.Actions() {
+			switch resource := action.GetResource().Resource; resource {
+			case "clusterroles", "clusterrolebindings", "serviceaccounts":
+				actions = append(actions, action.GetVerb()+" "+resource)
+			case "jobs":
+				if createAction, ok := action.(k8stesting.CreateAction); ok {
+					job = createAction.GetObject().(*batchv1.Job)
+				}
+			}
+		}
+		return actions, job
+	}
+
+	actions, _ := rbacActions(true)
+	assert.Contains(t, actions, "create clusterroles")
+	assert.Contains(t, actions, "delete clusterroles")
+
+	// pre-installed rbac
+	actions, job := rbacActions(false)
+	assert.Empty(t, actions)
+	if assert.NotNil(t, job) {
+		assert.Equal(t, "node-collector-sa", job.Spec.Template.Spec.ServiceAccountName)
+	}
+}
+
+func TestApplyAndCollectLogsUnavailable(t *testing.T) {
+	// job pod was garbage collected before its logs were read
+	jc, clientset := newTestCollector([]runtime.Object{&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}})
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(""))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+	_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.ErrorIs(t, err, ErrLogsUnavailable)
+
+	// pod still exists, output is empty
+	jobName := fmt.Sprintf("%s-%s", NodeCollectorName, ComputeHash(ObjectRef{Kind: "Node-Info", Name: "node-1", Namespace: "trivy-temp"}))
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      jobName + "-abcde",
+		Namespace: "trivy-temp",
+		Labels:    map[string]string{"batch.kubernetes.io/controller-uid": jobName},
+	}}
+	jc, clientset = newTestCollector([]runtime.Object{pod})
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(""))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+	output, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.NoError(t, err)
+	assert.Empty(t, output)
+}
+
+func TestApplyAndCollectCommonLabels(t *testing.T) {
+	commonLabels := map[string]string{"cost-center": "security", "team": "platform"}
+	jc, clientset := newTestCollector(nil,
+		WithCommonLabels(commonLabels),
+		WithJobLabels(map[string]string{"team": "trivy"}),
+		WithNodeConfig(true),
+		WithStdinConfig([]byte("checks: []")),
+	)
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+
+	_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.NoError(t, err)
+	created := make(map[string]map[string]string)
+	for _, action := range clientset.Actions() {
+		if createAction, ok := action.(k8stesting.CreateAction); ok {
+			obj, err := meta.Accessor(createAction.GetObject())
+			if assert.NoError(t, err) {
+				created[action.GetResource().Resource] = obj.GetLabels()
+			}
+		}
+	}
+	for _, resource := range []string{"namespaces", "clusterroles", "serviceaccounts", "clusterrolebindings", "configmaps", "jobs"} {
+		assert.Contains(t, created, resource)
+	}
+	for resource, objLabels := range created {
+		assert.Equal(t, "security", objLabels["cost-center"], resource)
+	}
+	assert.Equal(t, "true", created["namespaces"][TrivyAutoCreated])
+	// job labels take precedence
+	assert.Equal(t, "trivy", created["jobs"]["team"])
+	assert.Equal(t, "platform", created["serviceaccounts"]["team"])
+}
+
+func TestApplyAndCollectRequireExistingNamespace(t *testing.T) {
+	t.Run("missing namespace", func(t *testing.T) {
+		jc, clientset := newTestCollector(nil, WithRequireExistingNamespace(true))
+		completeJobsOnWatch(clientset, batchv1.JobComplete)
+
+		_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+		assert.ErrorIs(t, err, ErrNamespaceNotFound)
+		assert.ErrorContains(t, err, `"trivy-temp" must be created before running the collector`)
+		for _, action := range clientset.Actions() {
+			assert.NotEqual(t, "create", action.GetVerb(), action.GetResource().Resource)
+		}
+	})
+	t.Run("existing namespace", func(t *testing.T) {
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "trivy-temp"}}
+		jc, clientset := newTestCollector([]runtime.Object{namespace}, WithRequireExistingNamespace(true))
+		jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+		completeJobsOnWatch(clientset, batchv1.JobComplete)
+
+		_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,103 @@
+package jobs
+
+import (
+	"fmt"
+
+	"github.com/robfig/cron/v3"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// defaultSuccessfulJobsHistoryLimit and defaultFailedJobsHistoryLimit match the CronJob API defaults
+	defaultSuccessfulJobsHistoryLimit int32 = 3
+	defaultFailedJobsHistoryLimit     int32 = 1
+)
+
+// WithConcurrencyPolicy set how the CronJob treats concurrent runs, default to Forbid
+func WithConcurrencyPolicy(concurrencyPolicy batchv1.ConcurrencyPolicy) JobOption {
+	return func(j *JobBuilder) {
+		j.concurrencyPolicy = concurrencyPolicy
+	}
+}
+
+// WithSuccessfulJobsHistoryLimit set the number of successful jobs the CronJob keep
+func WithSuccessfulJobsHistoryLimit(limit int32) JobOption {
+	return func(j *JobBuilder) {
+		j.successfulJobsHistoryLimit = &limit
+	}
+}
+
+// WithFailedJobsHistoryLimit set the number of failed jobs the CronJob keep
+func WithFailedJobsHistoryLimit(limit int32) JobOption {
+	return func(j *JobBuilder) {
+		j.failedJobsHistoryLimit = &limit
+	}
+}
+
+// WithStartingDeadlineSeconds set the deadline to start a missed run, so runs missed while
+// the cronjob controller is down are not all started at once
+func WithStartingDeadlineSeconds(seconds int64) JobOption {
+	return func(j *JobBuilder) {
+		j.startingDeadlineSeconds = &seconds
+	}
+}
+
+// GetCronJob build the collector job and wrap it into a CronJob running on schedule (cron format),
+// concurrent runs are forbidden by default so a slow collection is not overlapped by the next one
+func GetCronJob(schedule string, opts ...JobOption) (*batchv1.CronJob, error) {
+	return NewJobBuilder(opts...).buildCronJob(schedule)
+}
+
+func (b *JobBuilder) buildCronJob(schedule string) (*batchv1.CronJob, error) {
+	if _, err := cron.ParseStandard(schedule); err != nil {
+		return nil, fmt.Errorf("invalid cron schedule %q: %w", schedule, err)
+	}
+	job, err := b.build()
+	if err != nil {
+		return nil, err
+	}
+	concurrencyPolicy := batchv1.ForbidConcurrent
+	if len(b.concurrencyPolicy) > 0 {
+		concurrencyPolicy = b.concurrencyPolicy
+	}
+	successfulJobsHistoryLimit := ptr.To(defaultSuccessfulJobsHistoryLimit)
+	if b.successfulJobsHistoryLimit != nil {
+		successfulJobsHistoryLimit = ptr.To(*b.successfulJobsHistoryLimit)
+	}
+	failedJobsHistoryLimit := ptr.To(defaultFailedJobsHistoryLimit)
+	if b.failedJobsHistoryLimit != nil {
+		failedJobsHistoryLimit = ptr.To(*b.failedJobsHistoryLimit)
+	}
+	var startingDeadlineSeconds *int64
+	if b.startingDeadlineSeconds != nil {
+		startingDeadlineSeconds = ptr.To(*b.startingDeadlineSeconds)
+	}
+	return &batchv1.CronJob{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: batchv1.SchemeGroupVersion.String(),
+			Kind:       "CronJob",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        job.Name,
+			Namespace:   job.Namespace,
+			Labels:      job.Labels,
+			Annotations: job.Annotations,
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:                   schedule,
+			ConcurrencyPolicy:          concurrencyPolicy,
+			SuccessfulJobsHistoryLimit: successfulJobsHistoryLimit,
+			FailedJobsHistoryLimit:     failedJobsHistoryLimit,
+			StartingDeadlineSeconds:    startingDeadlineSeconds,
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      job.Labels,
+					Annotations: job.Annotations,
+				},
+				Spec: job.Spec,
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,91 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestGetCronJob(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule string
+		wantErr  string
+	}{
+		{name: "cron expression", schedule: "0 */6 * * *"},
+		{name: "descriptor", schedule: "@daily"},
+		{name: "invalid expression", schedule: "0 */6 * *", wantErr: `invalid cron schedule "0 */6 * *"`},
+		{name: "invalid value", schedule: "61 * * * *", wantErr: `invalid cron schedule "61 * * * *"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cronJob, err := GetCronJob(tt.schedule,
+				WithTemplate(NodeCollectorName),
+				WithNamespace("trivy-temp"),
+				WithLabels(map[string]string{TrivyCollectorName: NodeCollectorName}),
+			)
+			if len(tt.wantErr) > 0 {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "CronJob", cronJob.Kind)
+			assert.Equal(t, "trivy-temp", cronJob.Namespace)
+			assert.Equal(t, tt.schedule, cronJob.Spec.Schedule)
+			assert.Equal(t, batchv1.ForbidConcurrent, cronJob.Spec.ConcurrencyPolicy)
+			assert.Equal(t, ptr.To[int32](3), cronJob.Spec.SuccessfulJobsHistoryLimit)
+			assert.Equal(t, ptr.To[int32](1), cronJob.Spec.FailedJobsHistoryLimit)
+			assert.Nil(t, cronJob.Spec.StartingDeadlineSeconds)
+			assert.Equal(t, NodeCollectorName, cronJob.Spec.JobTemplate.Labels[TrivyCollectorName])
+			job, err := GetJob(WithTemplate(NodeCollectorName), WithNamespace("trivy-temp"))
+			assert.NoError(t, err)
+			assert.Equal(t, job.Spec, cronJob.Spec.JobTemplate.Spec)
+		})
+	}
+}
+
+func TestGetCronJobOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		opt    JobOption
+		assert func(t *testing.T, spec batchv1.CronJobSpec)
+	}{
+		{
+			name: "concurrency policy",
+			opt:  WithConcurrencyPolicy(batchv1.ReplaceConcurrent),
+			assert: func(t *testing.T, spec batchv1.CronJobSpec) {
+				assert.Equal(t, batchv1.ReplaceConcurrent, spec.ConcurrencyPolicy)
+			},
+		},
+		{
+			name: "successful jobs history limit",
+			opt:  WithSuccessfulJobsHistoryLimit(0),
+			assert: func(t *testing.T, spec batchv1.CronJobSpec) {
+				assert.Equal(t, ptr.To[int32](0), spec.SuccessfulJobsHistoryLimit)
+			},
+		},
+		{
+			name: "failed jobs history limit",
+			opt:  WithFailedJobsHistoryLimit(5),
+			assert: func(t *testing.T, spec batchv1.CronJobSpec) {
+				assert.Equal(t, ptr.To[int32](5), spec.FailedJobsHistoryLimit)
+			},
+		},
+		{
+			name: "starting deadline seconds",
+			opt:  WithStartingDeadlineSeconds(300),
+			assert: func(t *testing.T, spec batchv1.CronJobSpec) {
+				assert.Equal(t, ptr.To[int64](300), spec.StartingDeadlineSeconds)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cronJob, err := GetCronJob("@hourly", WithTemplate(NodeCollectorName), tt.opt)
+			assert.NoError(t, err)
+			tt.assert(t, cronJob.Spec)
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+// CollectDecoder run the collector job on the node and returns a decoder over its logs stream, so large
+// outputs are decoded incrementally rather than read at once. The close func close the stream and clean up
+// the job, it must be called once decoding is done. The log read timeout and max log bytes apply to the
+// stream, output validators, post-processors and the result cache don't apply to decoded output
+func (jb *jobCollector) CollectDecoder(ctx context.Context, nodeName string) (*json.Decoder, func() error, error) {
+	jb = jb.snapshot()
+	if jb.optionErr != nil {
+		return nil, nil, jb.optionErr
+	}
+	ctx, release, err := jb.closable(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanups := []func(){release}
+	cleanup := func() {
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			cleanups[i]()
+		}
+	}
+	parentCtx := ctx
+	if jb.drainTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = drainContext(ctx, jb.clock, jb.drainTimeout)
+		cleanups = append(cleanups, cancel)
+	}
+	ctx, span := jb.tracer().Start(ctx, "CollectDecoder", trace.WithAttributes(attribute.String("node.name", nodeName)))
+	fail := func(err error) (*json.Decoder, func() error, error) {
+		if jb.lifecycle.closed() {
+			// collection aborted by Close
+			err = fmt.Errorf("%w: %w", ErrCollectorClosed, err)
+		}
+		endSpan(span, err)
+		cleanup()
+		return nil, nil, err
+	}
+
+	cacheKey := resultCacheKey{nodeName: nodeName, imageRef: jb.imageRef}
+	firstCollection := jb.resultCache == nil || !jb.resultCache.hasCollected(cacheKey)
+	run, err := jb.runCollectorJob(ctx, parentCtx, nodeName, firstCollection)
+	if err != nil {
+		return fail(err)
+	}
+	cleanups = append(cleanups, run.finish)
+
+	var logsStream io.ReadCloser
+	err = jb.tracePhase(ctx, "logs", func(ctx context.Context) error {
+		logsStream, err = jb.openLogsStream(ctx, run.job)
+		if err != nil {
+			return jb.checkNodeRemoved(ctx, nodeName, err)
+		}
+		return nil
+	}, attribute.String("job.name", run.job.Name))
+	if err != nil {
+		return fail(err)
+	}
+	if jb.resultCache != nil {
+		jb.resultCache.markCollected(cacheKey)
+	}
+	var closeOnce sync.Once
+	closeFunc := func() error {
+		err := logsStream.Close()
+		closeOnce.Do(func() {
+			endSpan(span, nil)
+			cleanup()
+		})
+		return err
+	}
+	return json.NewDecoder(logsStream), closeFunc, nil
+}
+
+// openLogsStream open the collector container logs stream, reads are bound by the log read timeout
+// and the max log bytes
+func (jb *jobCollector) openLogsStream(ctx context.Context, job *batchv1.Job) (io.ReadCloser, error) {
+	cancel := func() {}
+	if jb.logReadTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, jb.logReadTimeout)
+	}
+	logsStream, err := jb.logsReader.GetLogsByJobAndContainerName(ctx, job, NodeCollectorName)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("getting logs: %w", err)
+	}
+	// unblock reads once ctx is done
+	stop := context.AfterFunc(ctx, func() {
+		_ = logsStream.Close()
+	})
+	return &boundedLogsStream{
+		ReadCloser: logsStream,
+		ctx:        ctx,
+		timeout:    jb.logReadTimeout > 0,
+		maxBytes:   jb.maxLogBytes,
+		release: func() {
+			stop()
+			cancel()
+		},
+	}, nil
+}
+
+// boundedLogsStream is a logs stream failing with ErrLogReadTimeout once ctx deadline is exceeded
+// and with ErrMaxLogBytesExceeded once more than maxBytes are read (unbounded when maxBytes <= 0)
+type boundedLogsStream struct {
+	io.ReadCloser
+	ctx     context.Context
+	timeout bool
+	// maxBytes is the max log bytes, read counts the bytes read so far
+	maxBytes int64
+	read     int64
+	release  func()
+}
+
+func (s *boundedLogsStream) Read(p []byte) (int, error) {
+	if err := s.ctx.Err(); err != nil {
+		return 0, s.ctxErr(err)
+	}
+	if s.maxBytes > 0 {
+		// read one extra byte to detect output exceeding the cap
+		if remaining := s.maxBytes - s.read + 1; int64(len(p)) > remaining {
+			p = p[:remaining]
+		}
+	}
+	n, err := s.ReadCloser.Read(p)
+	if s.maxBytes > 0 && s.read+int64(n) > s.maxBytes {
+		n = int(s.maxBytes - s.read)
+		s.read = s.maxBytes
+		return n, ErrMaxLogBytesExceeded
+	}
+	s.read += int64(n)
+	if err != nil && !errors.Is(err, io.EOF) {
+		if ctxErr := s.ctx.Err(); ctxErr != nil {
+			// stream closed on ctx done
+			return n, s.ctxErr(ctxErr)
+		}
+		return n, fmt.Errorf("reading logs: %w", err)
+	}
+	return n, err
+}
+
+func (s *boundedLogsStream) ctxErr(err error) error {
+	if s.timeout && errors.Is(err, context.DeadlineExceeded) {
+		return ErrLogReadTimeout
+	}
+	return fmt.Errorf("reading logs: %w", err)
+}
+
+func (s *boundedLogsStream) Close() error {
+	s.release()
+	return s.ReadCloser.Close()
+}
@@ -0,0 +1,126 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	k8sapierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestCollectDecoder(t *testing.T) {
+	type finding struct {
+		ID string `json:"id"`
+	}
+	logsReader, logsWriter := io.Pipe()
+	jc, clientset := newTestCollector(nil)
+	jc.logsReader = &fakeLogsReader{logs: logsReader}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+	// the second finding is only written once the first one is decoded
+	firstDecoded := make(chan struct{})
+	go func() {
+		_, _ = io.WriteString(logsWriter, `[{"id":"1.1.1"},`)
+		<-firstDecoded
+		_, _ = io.WriteString(logsWriter, `{"id":"1.1.2"}]`)
+		_ = logsWriter.Close()
+	}()
+
+	decoder, closeFunc, err := jc.CollectDecoder(context.Background(), "node-1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	token, err := decoder.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, json.Delim('['), token)
+	var findings []finding
+	for decoder.More() {
+		var f finding
+		if !assert.NoError(t, decoder.Decode(&f)) {
+			break
+		}
+		if len(findings) == 0 {
+			close(firstDecoded)
+		}
+		findings = append(findings, f)
+	}
+	assert.Equal(t, []finding{{ID: "1.1.1"}, {ID: "1.1.2"}}, findings)
+
+	// job is deleted once closed
+	var jobName string
+	for _, action := range clientset.Actions() {
+		if createAction, ok := action.(k8stesting.CreateAction); ok && action.GetResource().Resource == "jobs" {
+			jobName = createAction.GetObject().(*batchv1.Job).Name
+		}
+	}
+	_, err = clientset.BatchV1().Jobs("trivy-temp").Get(context.Background(), jobName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, closeFunc())
+	_, err = clientset.BatchV1().Jobs("trivy-temp").Get(context.Background(), jobName, metav1.GetOptions{})
+	assert.True(t, k8sapierror.IsNotFound(err))
+}
+
+func TestCollectDecoderStartupArgs(t *testing.T) {
+	jc, clientset := newTestCollector(nil, WithResultCache(time.Minute), WithStartupArgs([]string{"--init-cache"}))
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+	collect := func() []string {
+		clientset.ClearActions()
+		jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+		_, closeFunc, err := jc.CollectDecoder(context.Background(), "node-1")
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		assert.NoError(t, closeFunc())
+		for _, action := range clientset.Actions() {
+			if createAction, ok := action.(k8stesting.CreateAction); ok && action.GetResource().Resource == "jobs" {
+				return createAction.GetObject().(*batchv1.Job).Spec.Template.Spec.Containers[0].Args
+			}
+		}
+		return nil
+	}
+
+	// startup args are added on the first collection only
+	assert.Equal(t, []string{"k8s", "--init-cache"}, collect())
+	assert.Equal(t, []string{"k8s"}, collect())
+}
+
+func TestCollectDecoderMaxLogBytes(t *testing.T) {
+	jc, clientset := newTestCollector(nil, WithMaxLogBytes(10))
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`[{"id":"1.1.1"},{"id":"1.1.2"}]`))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+
+	decoder, closeFunc, err := jc.CollectDecoder(context.Background(), "node-1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() {
+		assert.NoError(t, closeFunc())
+	}()
+	var findings []map[string]string
+	assert.ErrorIs(t, decoder.Decode(&findings), ErrMaxLogBytesExceeded)
+}
+
+func TestCollectDecoderLogReadTimeout(t *testing.T) {
+	// logs stream never ends
+	logsReader, logsWriter := io.Pipe()
+	defer logsWriter.Close()
+	jc, clientset := newTestCollector(nil, WithLogReadTimeout(50*time.Millisecond))
+	jc.logsReader = &fakeLogsReader{logs: logsReader}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+
+	decoder, closeFunc, err := jc.CollectDecoder(context.Background(), "node-1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() {
+		_ = closeFunc()
+	}()
+	var findings []map[string]string
+	assert.ErrorIs(t, decoder.Decode(&findings), ErrLogReadTimeout)
+}
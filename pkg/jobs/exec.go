@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// PodExecutor run commands in pod containers
+type PodExecutor interface {
+	Exec(ctx context.Context, namespace, podName, containerName string, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error
+}
+
+type spdyPodExecutor struct {
+	config    *rest.Config
+	clientset kubernetes.Interface
+}
+
+// NewSPDYPodExecutor instansiate new pod executor using the remotecommand SPDY executor
+func NewSPDYPodExecutor(config *rest.Config) (PodExecutor, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return newSPDYPodExecutor(config, clientset), nil
+}
+
+// newSPDYPodExecutor returns a pod executor sending exec requests with clientset
+func newSPDYPodExecutor(config *rest.Config, clientset kubernetes.Interface) PodExecutor {
+	return &spdyPodExecutor{
+		config:    config,
+		clientset: clientset,
+	}
+}
+
+// Exec run the command in the pod container without tty, streaming its output to stdout and stderr
+func (e *spdyPodExecutor) Exec(ctx context.Context, namespace, podName, containerName string, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	req := e.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(podName).
+		Namespace(namespace).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: containerName,
+			Command:   cmd,
+			Stdin:     stdin != nil,
+			Stdout:    stdout != nil,
+			Stderr:    stderr != nil,
+			TTY:       false,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(e.config, "POST", req.URL())
+	if err != nil {
+		return fmt.Errorf("creating executor: %w", err)
+	}
+	return executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+}
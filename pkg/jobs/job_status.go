@@ -0,0 +1,60 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// JobStatus is the normalized status of a collector job
+type JobStatus string
+
+const (
+	JobStatusPending   JobStatus = "Pending"
+	JobStatusRunning   JobStatus = "Running"
+	JobStatusSucceeded JobStatus = "Succeeded"
+	JobStatusFailed    JobStatus = "Failed"
+)
+
+// GetJobStatus fetch the job and returns its normalized status
+func (jb *jobCollector) GetJobStatus(ctx context.Context, job *batchv1.Job) (JobStatus, error) {
+	jb = jb.snapshot()
+	refreshedJob, err := jb.clientset.BatchV1().Jobs(job.Namespace).Get(ctx, job.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("getting job %q: %w", job.Namespace+"/"+job.Name, err)
+	}
+	return jobStatus(refreshedJob), nil
+}
+
+// jobStatus derive the job status from its conditions, then from its pods counts
+// when the job controller did not set a terminal condition yet
+func jobStatus(job *batchv1.Job) JobStatus {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return JobStatusSucceeded
+		case batchv1.JobFailed, batchv1.JobFailureTarget:
+			return JobStatusFailed
+		}
+	}
+	if job.Status.Active > 0 {
+		return JobStatusRunning
+	}
+	completions := int32(1)
+	if job.Spec.Completions != nil {
+		completions = *job.Spec.Completions
+	}
+	if job.Status.Succeeded > 0 && job.Status.Succeeded >= completions {
+		return JobStatusSucceeded
+	}
+	if job.Spec.BackoffLimit != nil && job.Status.Failed > *job.Spec.BackoffLimit {
+		return JobStatusFailed
+	}
+	return JobStatusPending
+}
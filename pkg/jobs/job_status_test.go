@@ -0,0 +1,101 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+)
+
+func TestJobStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		spec   batchv1.JobSpec
+		status batchv1.JobStatus
+		want   JobStatus
+	}{
+		{
+			name: "new job",
+			want: JobStatusPending,
+		},
+		{
+			name:   "active pod",
+			status: batchv1.JobStatus{Active: 1},
+			want:   JobStatusRunning,
+		},
+		{
+			name: "complete condition",
+			status: batchv1.JobStatus{Succeeded: 1, Conditions: []batchv1.JobCondition{
+				{Type: batchv1.JobComplete, Status: corev1.ConditionTrue},
+			}},
+			want: JobStatusSucceeded,
+		},
+		{
+			name: "failed condition",
+			status: batchv1.JobStatus{Failed: 1, Conditions: []batchv1.JobCondition{
+				{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"},
+			}},
+			want: JobStatusFailed,
+		},
+		{
+			name: "suspended condition",
+			status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+				{Type: batchv1.JobSuspended, Status: corev1.ConditionTrue},
+			}},
+			want: JobStatusPending,
+		},
+		{
+			name:   "succeeded pod without condition",
+			status: batchv1.JobStatus{Succeeded: 1},
+			want:   JobStatusSucceeded,
+		},
+		{
+			name:   "failed pod retried",
+			spec:   batchv1.JobSpec{BackoffLimit: ptr.To[int32](1)},
+			status: batchv1.JobStatus{Failed: 1},
+			want:   JobStatusPending,
+		},
+		{
+			name:   "failed pods over backoff limit",
+			spec:   batchv1.JobSpec{BackoffLimit: ptr.To[int32](0)},
+			status: batchv1.JobStatus{Failed: 1},
+			want:   JobStatusFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, jobStatus(&batchv1.Job{Spec: tt.spec, Status: tt.status}))
+		})
+	}
+}
+
+func TestGetJobStatus(t *testing.T) {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-collector-abc", Namespace: "trivy-temp"},
+		Status:     batchv1.JobStatus{Active: 1},
+	}
+	jc, _ := newTestCollector([]runtime.Object{job})
+
+	status, err := jc.GetJobStatus(context.Background(), &batchv1.Job{ObjectMeta: job.ObjectMeta})
+	assert.NoError(t, err)
+	assert.Equal(t, JobStatusRunning, status)
+
+	_, err = jc.GetJobStatus(context.Background(), &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "missing", Namespace: "trivy-temp"}})
+	assert.ErrorContains(t, err, `getting job "trivy-temp/missing"`)
+
+	// reads are not rate limited
+	jc, _ = newTestCollector([]runtime.Object{job}, WithQPSLimit(1, 1), WithCollectorClock(&fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}))
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.NoError(t, jc.throttle(ctx))
+	cancel()
+	assert.ErrorIs(t, jc.throttle(ctx), context.Canceled)
+	status, err = jc.GetJobStatus(ctx, &batchv1.Job{ObjectMeta: job.ObjectMeta})
+	assert.NoError(t, err)
+	assert.Equal(t, JobStatusRunning, status)
+}
@@ -0,0 +1,94 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// kubeletInfoPrefix prefix node-collector info entries collected from the kubelet config
+const kubeletInfoPrefix = "kubelet"
+
+// KubeletConfig is the kubelet configuration section of node-collector output
+type KubeletConfig struct {
+	AnonymousAuth                  bool
+	AuthorizationMode              string
+	ClientCAFile                   string
+	ReadOnlyPort                   int
+	StreamingConnectionIdleTimeout string
+	ProtectKernelDefaults          bool
+	MakeIPTablesUtilChains         bool
+	EventQPS                       int
+	TLSCertFile                    string
+	TLSPrivateKeyFile              string
+	RotateCertificates             bool
+	RotateServerCertificates       bool
+	// Values are all kubelet info entries keyed by node-collector check name
+	Values map[string][]interface{}
+}
+
+type nodeCollectorOutput struct {
+	Info map[string]nodeCollectorInfo `json:"info"`
+}
+
+type nodeCollectorInfo struct {
+	Values []interface{} `json:"values"`
+}
+
+// CollectKubeletConfig run the collector and parse the kubelet config section of its output.
+// node-collector has no arg to select a single collector, the full collection is run with the collector
+// config (node config mode and its rbac are not enabled by this method) and only the kubelet entries are parsed
+func (jb *jobCollector) CollectKubeletConfig(ctx context.Context, nodeName string) (*KubeletConfig, error) {
+	output, err := jb.ApplyAndCollect(ctx, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	return parseKubeletConfig([]byte(output))
+}
+
+// parseKubeletConfig parse the kubelet entries of node-collector output, missing entries are left zero
+func parseKubeletConfig(output []byte) (*KubeletConfig, error) {
+	var nodeInfo nodeCollectorOutput
+	if err := json.Unmarshal(output, &nodeInfo); err != nil {
+		return nil, fmt.Errorf("parsing node-collector output: %w", err)
+	}
+	config := &KubeletConfig{Values: make(map[string][]interface{})}
+	for name, info := range nodeInfo.Info {
+		if strings.HasPrefix(name, kubeletInfoPrefix) {
+			config.Values[name] = info.Values
+		}
+	}
+	config.AnonymousAuth = config.boolValue("kubeletAnonymousAuthArgumentSet")
+	config.AuthorizationMode = config.stringValue("kubeletAuthorizationModeArgumentSet")
+	config.ClientCAFile = config.stringValue("kubeletClientCaFileArgumentSet")
+	config.ReadOnlyPort = config.intValue("kubeletReadOnlyPortArgumentSet")
+	config.StreamingConnectionIdleTimeout = config.stringValue("kubeletStreamingConnectionIdleTimeoutArgumentSet")
+	config.ProtectKernelDefaults = config.boolValue("kubeletProtectKernelDefaultsArgumentSet")
+	config.MakeIPTablesUtilChains = config.boolValue("kubeletMakeIptablesUtilChainsArgumentSet")
+	config.EventQPS = config.intValue("kubeletEventQpsArgumentSet")
+	config.TLSCertFile = config.stringValue("kubeletTlsCertFileTlsArgumentSet")
+	config.TLSPrivateKeyFile = config.stringValue("kubeletTlsPrivateKeyFileArgumentSet")
+	config.RotateCertificates = config.boolValue("kubeletRotateCertificatesArgumentSet")
+	config.RotateServerCertificates = config.boolValue("kubeletRotateKubeletServerCertificateArgumentSet")
+	return config, nil
+}
+
+func (c *KubeletConfig) stringValue(name string) string {
+	values := c.Values[name]
+	if len(values) == 0 || values[0] == nil {
+		return ""
+	}
+	return fmt.Sprint(values[0])
+}
+
+func (c *KubeletConfig) boolValue(name string) bool {
+	b, _ := strconv.ParseBool(c.stringValue(name))
+	return b
+}
+
+func (c *KubeletConfig) intValue(name string) int {
+	i, _ := strconv.Atoi(c.stringValue(name))
+	return i
+}
@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const sampleNodeCollectorOutput = `{
+  "apiVersion": "v1",
+  "kind": "NodeInfo",
+  "type": "worker",
+  "info": {
+    "kubeletAnonymousAuthArgumentSet": {"values": [false]},
+    "kubeletAuthorizationModeArgumentSet": {"values": ["Webhook"]},
+    "kubeletClientCaFileArgumentSet": {"values": ["/etc/kubernetes/pki/ca.crt"]},
+    "kubeletReadOnlyPortArgumentSet": {"values": [0]},
+    "kubeletStreamingConnectionIdleTimeoutArgumentSet": {"values": ["4h0m0s"]},
+    "kubeletProtectKernelDefaultsArgumentSet": {"values": ["true"]},
+    "kubeletEventQpsArgumentSet": {"values": [5]},
+    "kubeletRotateCertificatesArgumentSet": {"values": [true]},
+    "kubeletConfFilePermissions": {"values": [600]},
+    "containerNetworkInterfaceFilePermissions": {"values": [644]}
+  }
+}`
+
+func TestParseKubeletConfig(t *testing.T) {
+	config, err := parseKubeletConfig([]byte(sampleNodeCollectorOutput))
+	assert.NoError(t, err)
+	assert.False(t, config.AnonymousAuth)
+	assert.Equal(t, "Webhook", config.AuthorizationMode)
+	assert.Equal(t, "/etc/kubernetes/pki/ca.crt", config.ClientCAFile)
+	assert.Equal(t, 0, config.ReadOnlyPort)
+	assert.Equal(t, "4h0m0s", config.StreamingConnectionIdleTimeout)
+	assert.True(t, config.ProtectKernelDefaults)
+	assert.Equal(t, 5, config.EventQPS)
+	assert.True(t, config.RotateCertificates)
+	// missing entries are left zero
+	assert.Empty(t, config.TLSCertFile)
+	assert.False(t, config.RotateServerCertificates)
+	// only kubelet entries are kept
+	assert.Len(t, config.Values, 9)
+	assert.NotContains(t, config.Values, "containerNetworkInterfaceFilePermissions")
+
+	_, err = parseKubeletConfig([]byte(`panic: runtime error`))
+	assert.ErrorContains(t, err, "parsing node-collector output")
+}
+
+func TestCollectKubeletConfig(t *testing.T) {
+	jc, clientset := newTestCollector(nil)
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(sampleNodeCollectorOutput))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+
+	config, err := jc.CollectKubeletConfig(context.Background(), "node-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Webhook", config.AuthorizationMode)
+	// collector config is used as is, no node config rbac is created
+	var args []string
+	for _, action := range clientset.Actions() {
+		if createAction, ok := action.(k8stesting.CreateAction); ok {
+			assert.NotContains(t, []string{"clusterroles", "clusterrolebindings", "serviceaccounts"}, action.GetResource().Resource)
+			if action.GetResource().Resource == "jobs" {
+				args = createAction.GetObject().(*batchv1.Job).Spec.Template.Spec.Containers[0].Args
+			}
+		}
+	}
+	assert.Equal(t, []string{"k8s"}, args)
+}
@@ -5,10 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -17,23 +19,72 @@ var podControlledByJobNotFoundErr = errors.New("pod for job not found")
 // LogsReader responsible for collecting container status and logs
 type LogsReader interface {
 	GetLogsByJobAndContainerName(ctx context.Context, job *batchv1.Job, containerName string) (io.ReadCloser, error)
+	GetLogsByPodAndContainer(ctx context.Context, namespace, podName, containerName string) (io.ReadCloser, error)
 	GetTerminatedContainersStatusesByJob(ctx context.Context, job *batchv1.Job) (map[string]*corev1.ContainerStateTerminated, error)
 }
 
+const (
+	defaultPodReadyTimeout = time.Minute
+	defaultPodPollInterval = time.Second
+)
+
 type logsReader struct {
 	clientset kubernetes.Interface
+	// podReadyTimeout duration to wait for the job pod to be running before reading logs
+	podReadyTimeout time.Duration
+	podPollInterval time.Duration
+	podLogOptions   *corev1.PodLogOptions
+	// insecureKubeletLogs skip kubelet serving certificate verification when reading logs
+	insecureKubeletLogs bool
+}
+
+type LogsReaderOption func(*logsReader)
+
+func WithPodReadyTimeout(timeout time.Duration) LogsReaderOption {
+	return func(r *logsReader) {
+		r.podReadyTimeout = timeout
+	}
+}
+
+func WithPodPollInterval(interval time.Duration) LogsReaderOption {
+	return func(r *logsReader) {
+		r.podPollInterval = interval
+	}
+}
+
+// WithPodLogOptions set pod log options (e.g. Timestamps, TailLines, LimitBytes),
+// Container and Follow are always set by the reader
+func WithPodLogOptions(podLogOptions *corev1.PodLogOptions) LogsReaderOption {
+	return func(r *logsReader) {
+		r.podLogOptions = podLogOptions
+	}
+}
+
+// WithInsecureKubeletLogs skip kubelet serving certificate verification when the api server
+// proxy logs from the kubelet, for clusters with self-signed kubelet certs
+func WithInsecureKubeletLogs(insecure bool) LogsReaderOption {
+	return func(r *logsReader) {
+		r.insecureKubeletLogs = insecure
+	}
 }
 
 // NewLogsReader instansiate new log reader
-func NewLogsReader(clientset kubernetes.Interface) LogsReader {
-	return &logsReader{
-		clientset: clientset,
+func NewLogsReader(clientset kubernetes.Interface, opts ...LogsReaderOption) LogsReader {
+	r := &logsReader{
+		clientset:       clientset,
+		podReadyTimeout: defaultPodReadyTimeout,
+		podPollInterval: defaultPodPollInterval,
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
-// GetLogsByJobAndContainerName collect logs from container and return it reader
+// GetLogsByJobAndContainerName collect logs from container and return it reader,
+// it waits for the job pod to be running or terminated before reading logs
 func (r *logsReader) GetLogsByJobAndContainerName(ctx context.Context, job *batchv1.Job, containerName string) (io.ReadCloser, error) {
-	pod, err := r.getPodByJob(ctx, job)
+	pod, err := r.waitForPodByJob(ctx, job)
 	if err != nil {
 		return nil, fmt.Errorf("getting pod controlled by job: %q: %w", job.Namespace+"/"+job.Name, err)
 	}
@@ -41,11 +92,22 @@ func (r *logsReader) GetLogsByJobAndContainerName(ctx context.Context, job *batc
 		return nil, fmt.Errorf("getting pod controlled by job: %q: %w", job.Namespace+"/"+job.Name, podControlledByJobNotFoundErr)
 	}
 
-	return r.clientset.CoreV1().Pods(pod.Namespace).
-		GetLogs(pod.Name, &corev1.PodLogOptions{
-			Follow:    true,
-			Container: containerName,
-		}).Stream(ctx)
+	return r.GetLogsByPodAndContainer(ctx, pod.Namespace, pod.Name, containerName)
+}
+
+// GetLogsByPodAndContainer collect logs from pod container and return it reader
+func (r *logsReader) GetLogsByPodAndContainer(ctx context.Context, namespace, podName, containerName string) (io.ReadCloser, error) {
+	podLogOptions := &corev1.PodLogOptions{}
+	if r.podLogOptions != nil {
+		podLogOptions = r.podLogOptions.DeepCopy()
+	}
+	podLogOptions.Follow = true
+	podLogOptions.Container = containerName
+	if r.insecureKubeletLogs {
+		podLogOptions.InsecureSkipTLSVerifyBackend = true
+	}
+	return r.clientset.CoreV1().Pods(namespace).
+		GetLogs(podName, podLogOptions).Stream(ctx)
 }
 
 // GetTerminatedContainersStatusesByJob collect information about contianer status by job
@@ -58,18 +120,36 @@ func (r *logsReader) GetTerminatedContainersStatusesByJob(ctx context.Context, j
 	return statuses, nil
 }
 
+// waitForPodByJob poll job pod until it is running or terminated, it returns
+// the last seen pod (if any) once podReadyTimeout elapse
+func (r *logsReader) waitForPodByJob(ctx context.Context, job *batchv1.Job) (*corev1.Pod, error) {
+	var pod *corev1.Pod
+	err := wait.PollUntilContextTimeout(ctx, r.podPollInterval, r.podReadyTimeout, true, func(ctx context.Context) (bool, error) {
+		var err error
+		pod, err = r.getPodByJob(ctx, job)
+		if err != nil {
+			return false, err
+		}
+		if pod == nil {
+			return false, nil
+		}
+		switch pod.Status.Phase {
+		case corev1.PodRunning, corev1.PodSucceeded, corev1.PodFailed:
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil && !wait.Interrupted(err) {
+		return nil, err
+	}
+	return pod, nil
+}
+
 func (r *logsReader) getPodByJob(ctx context.Context, job *batchv1.Job) (*corev1.Pod, error) {
-	refreshedJob, err := r.clientset.BatchV1().Jobs(job.Namespace).Get(ctx, job.Name, metav1.GetOptions{})
+	selector, err := getJobPodsSelector(ctx, r.clientset, job)
 	if err != nil {
 		return nil, err
 	}
-	matchingLabelKey := "controller-uid"
-	matchingLabelValue := refreshedJob.Spec.Selector.MatchLabels[matchingLabelKey]
-	if len(matchingLabelValue) == 0 {
-		matchingLabelKey = "batch.kubernetes.io/controller-uid" // for k8s v1.27.x and above
-		matchingLabelValue = refreshedJob.Spec.Selector.MatchLabels[matchingLabelKey]
-	}
-	selector := fmt.Sprintf("%s=%s", matchingLabelKey, matchingLabelValue)
 	podList, err := r.clientset.CoreV1().Pods(job.Namespace).List(ctx, metav1.ListOptions{
 		LabelSelector: selector})
 	if err != nil {
@@ -81,6 +161,32 @@ func (r *logsReader) getPodByJob(ctx context.Context, job *batchv1.Job) (*corev1
 	return nil, nil
 }
 
+// getJobPodsSelector returns the label selector matching pods controlled by the job
+func getJobPodsSelector(ctx context.Context, clientset kubernetes.Interface, job *batchv1.Job) (string, error) {
+	refreshedJob, err := clientset.BatchV1().Jobs(job.Namespace).Get(ctx, job.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	if refreshedJob.Spec.Selector == nil {
+		return "", fmt.Errorf("job %q has no pod selector", job.Namespace+"/"+job.Name)
+	}
+	matchingLabelKey := "controller-uid"
+	matchingLabelValue := refreshedJob.Spec.Selector.MatchLabels[matchingLabelKey]
+	if len(matchingLabelValue) == 0 {
+		matchingLabelKey = "batch.kubernetes.io/controller-uid" // for k8s v1.27.x and above
+		matchingLabelValue = refreshedJob.Spec.Selector.MatchLabels[matchingLabelKey]
+	}
+	if len(matchingLabelValue) == 0 {
+		// manual selector
+		selector, err := metav1.LabelSelectorAsSelector(refreshedJob.Spec.Selector)
+		if err != nil {
+			return "", err
+		}
+		return selector.String(), nil
+	}
+	return fmt.Sprintf("%s=%s", matchingLabelKey, matchingLabelValue), nil
+}
+
 // GetTerminatedContainersStatusesByPod collect information about contianer status by pod
 func GetTerminatedContainersStatusesByPod(pod *corev1.Pod) map[string]*corev1.ContainerStateTerminated {
 	states := make(map[string]*corev1.ContainerStateTerminated)
@@ -0,0 +1,151 @@
+package jobs
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+)
+
+func TestGetLogsByPodAndContainer(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-collector-abc", Namespace: "trivy-temp"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: NodeCollectorName}},
+		},
+	})
+	lr := NewLogsReader(clientset)
+
+	logsStream, err := lr.GetLogsByPodAndContainer(context.Background(), "trivy-temp", "node-collector-abc", NodeCollectorName)
+	assert.NoError(t, err)
+	defer logsStream.Close()
+	output, err := io.ReadAll(logsStream)
+	assert.NoError(t, err)
+	// fake clientset always stream static logs
+	assert.Equal(t, "fake logs", string(output))
+}
+
+func TestGetLogsByPodAndContainerPodLogOptions(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	lr := NewLogsReader(clientset, WithPodLogOptions(&corev1.PodLogOptions{
+		Container:  "other",
+		Follow:     false,
+		Timestamps: true,
+		TailLines:  ptr.To[int64](10),
+	}))
+
+	logsStream, err := lr.GetLogsByPodAndContainer(context.Background(), "trivy-temp", "node-collector-abc", NodeCollectorName)
+	assert.NoError(t, err)
+	defer logsStream.Close()
+	actions := clientset.Actions()
+	if assert.Len(t, actions, 1) {
+		podLogOptions, ok := actions[0].(k8stesting.GenericAction).GetValue().(*corev1.PodLogOptions)
+		assert.True(t, ok)
+		// reader fields can't be overridden
+		assert.Equal(t, &corev1.PodLogOptions{
+			Container:  NodeCollectorName,
+			Follow:     true,
+			Timestamps: true,
+			TailLines:  ptr.To[int64](10),
+		}, podLogOptions)
+	}
+}
+
+func TestGetLogsByPodAndContainerInsecureKubeletLogs(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	lr := NewLogsReader(clientset, WithInsecureKubeletLogs(true))
+
+	logsStream, err := lr.GetLogsByPodAndContainer(context.Background(), "trivy-temp", "node-collector-abc", NodeCollectorName)
+	assert.NoError(t, err)
+	defer logsStream.Close()
+	actions := clientset.Actions()
+	if assert.Len(t, actions, 1) {
+		podLogOptions, ok := actions[0].(k8stesting.GenericAction).GetValue().(*corev1.PodLogOptions)
+		assert.True(t, ok)
+		assert.True(t, podLogOptions.InsecureSkipTLSVerifyBackend)
+	}
+}
+
+func TestGetLogsByJobAndContainerNameWaitForPod(t *testing.T) {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-collector", Namespace: "trivy-temp"},
+		Spec: batchv1.JobSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"batch.kubernetes.io/controller-uid": "abc"},
+			},
+		},
+	}
+	clientset := fake.NewSimpleClientset(job)
+	lr := NewLogsReader(clientset, WithPodPollInterval(10*time.Millisecond), WithPodReadyTimeout(5*time.Second))
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_, _ = clientset.CoreV1().Pods("trivy-temp").Create(context.Background(), &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "node-collector-abc",
+				Namespace: "trivy-temp",
+				Labels:    map[string]string{"batch.kubernetes.io/controller-uid": "abc"},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}, metav1.CreateOptions{})
+	}()
+
+	logsStream, err := lr.GetLogsByJobAndContainerName(context.Background(), job, NodeCollectorName)
+	assert.NoError(t, err)
+	defer logsStream.Close()
+	output, err := io.ReadAll(logsStream)
+	assert.NoError(t, err)
+	assert.Equal(t, "fake logs", string(output))
+}
+
+func TestGetLogsByJobAndContainerNamePodNotFound(t *testing.T) {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-collector", Namespace: "trivy-temp"},
+		Spec: batchv1.JobSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"batch.kubernetes.io/controller-uid": "abc"},
+			},
+		},
+	}
+	lr := NewLogsReader(fake.NewSimpleClientset(job), WithPodPollInterval(10*time.Millisecond), WithPodReadyTimeout(50*time.Millisecond))
+
+	_, err := lr.GetLogsByJobAndContainerName(context.Background(), job, NodeCollectorName)
+	assert.True(t, IsPodControlledByJobNotFound(err))
+}
+
+func TestGetLogsByJobAndContainerNameManualSelector(t *testing.T) {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-collector", Namespace: "trivy-temp"},
+		Spec: batchv1.JobSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"org.example/app": "node-collector"},
+			},
+			ManualSelector: ptr.To[bool](true),
+		},
+	}
+	clientset := fake.NewSimpleClientset(job, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "node-collector-abc",
+			Namespace: "trivy-temp",
+			Labels:    map[string]string{"org.example/app": "node-collector"},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodSucceeded},
+	})
+	lr := NewLogsReader(clientset)
+
+	// pod is found by the manual selector labels
+	logsStream, err := lr.GetLogsByJobAndContainerName(context.Background(), job, NodeCollectorName)
+	assert.NoError(t, err)
+	defer logsStream.Close()
+	output, err := io.ReadAll(logsStream)
+	assert.NoError(t, err)
+	assert.Equal(t, "fake logs", string(output))
+}
@@ -0,0 +1,106 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+// ErrUnknownProfile is returned by the collector when WithProfile is given a profile name which is not supported
+var ErrUnknownProfile = errors.New("unknown collector profile")
+
+// collector profiles, see WithProfile
+const (
+	ProfileEKS        = "eks"
+	ProfileGKE        = "gke"
+	ProfileHardened   = "hardened"
+	ProfilePrivileged = "privileged"
+)
+
+// tolerateAllTaints let the collector run on tainted nodes (e.g. gpu or dedicated node groups)
+var tolerateAllTaints = []corev1.Toleration{{Operator: corev1.TolerationOpExists}}
+
+// excludeNodesAffinity returns a node affinity excluding nodes with label set to one of values
+func excludeNodesAffinity(label string, values ...string) *corev1.Affinity {
+	return &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+					MatchExpressions: []corev1.NodeSelectorRequirement{{
+						Key:      label,
+						Operator: corev1.NodeSelectorOpNotIn,
+						Values:   values,
+					}},
+				}},
+			},
+		},
+	}
+}
+
+// profiles are curated option sets by profile name
+var profiles = map[string]func() []CollectorOption{
+	// fargate nodes can't run pods with host path volumes
+	ProfileEKS: func() []CollectorOption {
+		return []CollectorOption{
+			WithJobTolerations(tolerateAllTaints),
+			WithJobAffinity(excludeNodesAffinity("eks.amazonaws.com/compute-type", "fargate")),
+		}
+	},
+	// gVisor sandbox nodes don't expose the host files
+	ProfileGKE: func() []CollectorOption {
+		return []CollectorOption{
+			WithJobTolerations(tolerateAllTaints),
+			WithJobAffinity(excludeNodesAffinity("sandbox.gke.io/runtime", "gvisor")),
+		}
+	},
+	// read-only collection with the least privileges
+	ProfileHardened: func() []CollectorOption {
+		return []CollectorOption{
+			WithContainerSecurityContext(&corev1.SecurityContext{
+				Privileged:               ptr.To(false),
+				AllowPrivilegeEscalation: ptr.To(false),
+				ReadOnlyRootFilesystem:   ptr.To(true),
+				Capabilities: &corev1.Capabilities{
+					Drop: []corev1.Capability{"ALL"},
+				},
+			}),
+			WithPodSpecSecurityContext(&corev1.PodSecurityContext{
+				SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+			}),
+		}
+	},
+	// privileged collection on every node, for bare-metal clusters with restricted host files
+	ProfilePrivileged: func() []CollectorOption {
+		return []CollectorOption{
+			WithJobTolerations(tolerateAllTaints),
+			WithContainerSecurityContext(&corev1.SecurityContext{
+				Privileged: ptr.To(true),
+				RunAsUser:  ptr.To[int64](0),
+			}),
+		}
+	},
+}
+
+// WithProfile apply a curated option set by name (ProfileEKS, ProfileGKE, ProfileHardened or ProfilePrivileged),
+// options set after the profile override it. Collections and Apply fail with ErrUnknownProfile for unknown profiles
+func WithProfile(name string) CollectorOption {
+	return func(jc *jobCollector) {
+		profile, ok := profiles[name]
+		if !ok {
+			var names []string
+			for profileName := range profiles {
+				names = append(names, profileName)
+			}
+			slices.Sort(names)
+			jc.optionErr = errors.Join(jc.optionErr, fmt.Errorf("%w %q, supported: %s", ErrUnknownProfile, name, strings.Join(names, ", ")))
+			return
+		}
+		for _, opt := range profile() {
+			opt(jc)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestProfile(t *testing.T) {
+	tests := []struct {
+		name   string
+		assert func(t *testing.T, podSpec corev1.PodSpec)
+	}{
+		{
+			name: ProfileEKS,
+			assert: func(t *testing.T, podSpec corev1.PodSpec) {
+				assert.Contains(t, podSpec.Tolerations, corev1.Toleration{Operator: corev1.TolerationOpExists})
+				expression := podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions[0]
+				assert.Equal(t, "eks.amazonaws.com/compute-type", expression.Key)
+				assert.Equal(t, corev1.NodeSelectorOpNotIn, expression.Operator)
+			},
+		},
+		{
+			name: ProfileGKE,
+			assert: func(t *testing.T, podSpec corev1.PodSpec) {
+				assert.Contains(t, podSpec.Tolerations, corev1.Toleration{Operator: corev1.TolerationOpExists})
+				expression := podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions[0]
+				assert.Equal(t, "sandbox.gke.io/runtime", expression.Key)
+			},
+		},
+		{
+			name: ProfileHardened,
+			assert: func(t *testing.T, podSpec corev1.PodSpec) {
+				securityContext := podSpec.Containers[0].SecurityContext
+				assert.Equal(t, ptr.To(false), securityContext.AllowPrivilegeEscalation)
+				assert.Equal(t, ptr.To(true), securityContext.ReadOnlyRootFilesystem)
+				assert.Equal(t, []corev1.Capability{"ALL"}, securityContext.Capabilities.Drop)
+				assert.Equal(t, corev1.SeccompProfileTypeRuntimeDefault, podSpec.SecurityContext.SeccompProfile.Type)
+			},
+		},
+		{
+			name: ProfilePrivileged,
+			assert: func(t *testing.T, podSpec corev1.PodSpec) {
+				assert.Contains(t, podSpec.Tolerations, corev1.Toleration{Operator: corev1.TolerationOpExists})
+				assert.Equal(t, ptr.To(true), podSpec.Containers[0].SecurityContext.Privileged)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jc, _ := newTestCollector(nil, WithProfile(tt.name))
+			job, err := jc.Apply(context.Background(), "node-1")
+			assert.NoError(t, err)
+			tt.assert(t, job.Spec.Template.Spec)
+		})
+	}
+}
+
+func TestProfileOverride(t *testing.T) {
+	securityContext := &corev1.SecurityContext{Privileged: ptr.To(false)}
+	tolerations := []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "scanner"}}
+	jc, _ := newTestCollector(nil,
+		WithProfile(ProfilePrivileged),
+		WithContainerSecurityContext(securityContext),
+		WithJobTolerations(tolerations),
+	)
+	job, err := jc.Apply(context.Background(), "node-1")
+	assert.NoError(t, err)
+	podSpec := job.Spec.Template.Spec
+	assert.Equal(t, securityContext, podSpec.Containers[0].SecurityContext)
+	assert.NotContains(t, podSpec.Tolerations, corev1.Toleration{Operator: corev1.TolerationOpExists})
+	assert.Contains(t, podSpec.Tolerations, tolerations[0])
+}
+
+func TestUnknownProfile(t *testing.T) {
+	jc, clientset := newTestCollector(nil, WithProfile("ekss"))
+	_, err := jc.Apply(context.Background(), "node-1")
+	assert.ErrorIs(t, err, ErrUnknownProfile)
+	assert.ErrorContains(t, err, `"ekss", supported: eks, gke, hardened, privileged`)
+	_, err = jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.ErrorIs(t, err, ErrUnknownProfile)
+	_, _, err = jc.CollectDecoder(context.Background(), "node-1")
+	assert.ErrorIs(t, err, ErrUnknownProfile)
+	// nothing is created
+	assert.Empty(t, clientset.Actions())
+}
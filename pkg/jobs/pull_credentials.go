@@ -0,0 +1,60 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WithDynamicPullCredentials fetch a short-lived pull token (e.g. ECR) on each collection, it is written
+// to an ephemeral dockerconfigjson secret referenced by the job and deleted once the collection is done
+func WithDynamicPullCredentials(pullCredentials func(ctx context.Context) (dockerConfigJSON []byte, err error)) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.pullCredentials = pullCredentials
+	}
+}
+
+// pullSecretName returns the name of the job ephemeral image pull secret
+func pullSecretName(job *batchv1.Job) string {
+	return job.Name + "-pull"
+}
+
+// usePullSecret add the ephemeral image pull secret to the job
+func usePullSecret(job *batchv1.Job) {
+	podSpec := &job.Spec.Template.Spec
+	podSpec.ImagePullSecrets = append(podSpec.ImagePullSecrets, corev1.LocalObjectReference{Name: pullSecretName(job)})
+}
+
+// createPullSecret fetch the pull credentials and create the job ephemeral image pull secret
+func (jb *jobCollector) createPullSecret(ctx context.Context, job *batchv1.Job) error {
+	dockerConfigJSON, err := jb.pullCredentials(ctx)
+	if err != nil {
+		return fmt.Errorf("fetching pull credentials: %w", err)
+	}
+	if err := jb.throttle(ctx); err != nil {
+		return err
+	}
+	_, err = jb.clientset.CoreV1().Secrets(job.Namespace).Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pullSecretName(job),
+			Namespace: job.Namespace,
+			Labels:    job.Labels,
+		},
+		Type: corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{corev1.DockerConfigJsonKey: dockerConfigJSON},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("creating image pull secret: %w", err)
+	}
+	return nil
+}
+
+// deletePullSecret delete the job ephemeral image pull secret, even when ctx is cancelled
+func (jb *jobCollector) deletePullSecret(ctx context.Context, job *batchv1.Job) {
+	ctx = context.WithoutCancel(ctx)
+	_ = jb.throttle(ctx)
+	_ = jb.clientset.CoreV1().Secrets(job.Namespace).Delete(ctx, pullSecretName(job), jb.deleteOptions())
+}
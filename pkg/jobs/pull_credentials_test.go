@@ -0,0 +1,74 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sapierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestApplyAndCollectDynamicPullCredentials(t *testing.T) {
+	dockerConfigJSON := []byte(`{"auths":{"123456789012.dkr.ecr.eu-west-1.amazonaws.com":{"auth":"dG9rZW4="}}}`)
+	jc, clientset := newTestCollector(nil, WithDynamicPullCredentials(func(ctx context.Context) ([]byte, error) {
+		return dockerConfigJSON, nil
+	}))
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+	// record the secrets while the job runs
+	secrets := make(chan *corev1.SecretList, 1)
+	clientset.PrependWatchReactor("jobs", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		go func() {
+			secretList, _ := clientset.CoreV1().Secrets(action.GetNamespace()).List(context.Background(), metav1.ListOptions{})
+			secrets <- secretList
+		}()
+		return false, nil, nil
+	})
+
+	_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.NoError(t, err)
+
+	var job *batchv1.Job
+	for _, action := range clientset.Actions() {
+		if createAction, ok := action.(k8stesting.CreateAction); ok && action.GetResource().Resource == "jobs" {
+			job = createAction.GetObject().(*batchv1.Job)
+		}
+	}
+	if !assert.NotNil(t, job) {
+		return
+	}
+	secretName := job.Name + "-pull"
+	// secret exists while the job runs
+	secretList := <-secrets
+	if assert.Len(t, secretList.Items, 1) {
+		assert.Equal(t, secretName, secretList.Items[0].Name)
+		assert.Equal(t, corev1.SecretTypeDockerConfigJson, secretList.Items[0].Type)
+		assert.Equal(t, dockerConfigJSON, secretList.Items[0].Data[".dockerconfigjson"])
+	}
+	// secret is referenced by the job
+	assert.Contains(t, job.Spec.Template.Spec.ImagePullSecrets, corev1.LocalObjectReference{Name: secretName})
+	// secret is deleted once done
+	_, err = clientset.CoreV1().Secrets("trivy-temp").Get(context.Background(), secretName, metav1.GetOptions{})
+	assert.True(t, k8sapierror.IsNotFound(err))
+}
+
+func TestApplyAndCollectDynamicPullCredentialsError(t *testing.T) {
+	jc, clientset := newTestCollector(nil, WithDynamicPullCredentials(func(ctx context.Context) ([]byte, error) {
+		return nil, errors.New("token expired")
+	}))
+
+	_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.ErrorContains(t, err, "fetching pull credentials: token expired")
+	// job is not created
+	for _, action := range clientset.Actions() {
+		assert.False(t, action.GetVerb() == "create" && action.GetResource().Resource == "jobs")
+	}
+}
@@ -2,14 +2,16 @@ package jobs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	k8sapierror "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
@@ -18,47 +20,141 @@ import (
 
 var defaultResyncDuration = 30 * time.Minute
 
+// ErrJobFailed is returned when the job reaches the Failed condition
+var ErrJobFailed = errors.New("job failed")
+
+// defaultRecreatePollInterval is the interval an evicted job creation is retried at while the deleted job is not gone
+const defaultRecreatePollInterval = time.Second
+
 type runnableJob struct {
 	clientset  kubernetes.Interface
 	logsReader LogsReader
 	job        *batchv1.Job // job to be run
+	template   *batchv1.Job // job as defined, to recreate it
+	onFailed   func(job *batchv1.Job, reason string)
+	// evictionRetries is the number of times the job is recreated when its pod is evicted
+	evictionRetries int
+	// evictions is the number of times the job was recreated
+	evictions int
+	// deleteOptions, throttle and clock are used to recreate the job
+	deleteOptions metav1.DeleteOptions
+	throttle      func(ctx context.Context) error
+	clock         Clock
+	onCreated     func(ctx context.Context, job *batchv1.Job) error
+}
+
+type RunnableJobOption func(*runnableJob)
+
+// WithOnFailed set a callback invoked with the job and the condition reason
+// (e.g. BackoffLimitExceeded) when the job reaches the Failed condition
+func WithOnFailed(onFailed func(job *batchv1.Job, reason string)) RunnableJobOption {
+	return func(r *runnableJob) {
+		r.onFailed = onFailed
+	}
+}
+
+// WithPodEvictionRetries recreate the job up to retries times when its pod is evicted
+// (e.g. spot node reclaimed) instead of relying on the job backoffLimit,
+// the job is left to its backoffLimit once retries are exhausted
+func WithPodEvictionRetries(retries int) RunnableJobOption {
+	return func(r *runnableJob) {
+		r.evictionRetries = retries
+	}
+}
+
+// WithDeleteOptions set the options of the evicted job delete, default to background propagation
+func WithDeleteOptions(deleteOptions metav1.DeleteOptions) RunnableJobOption {
+	return func(r *runnableJob) {
+		r.deleteOptions = deleteOptions
+	}
+}
+
+// WithThrottle set a func blocking until the evicted job delete and create calls are permitted (e.g. a rate limiter)
+func WithThrottle(throttle func(ctx context.Context) error) RunnableJobOption {
+	return func(r *runnableJob) {
+		r.throttle = throttle
+	}
+}
+
+// WithRunnableJobClock set the clock used to wait for the evicted job to be gone
+func WithRunnableJobClock(clock Clock) RunnableJobOption {
+	return func(r *runnableJob) {
+		r.clock = clock
+	}
+}
+
+// WithOnCreated set a hook called with the job once it is created (or recreated after an eviction),
+// e.g. to set the job as owner of its resources. Run fails with the hook error
+func WithOnCreated(onCreated func(ctx context.Context, job *batchv1.Job) error) RunnableJobOption {
+	return func(r *runnableJob) {
+		r.onCreated = onCreated
+	}
 }
 
 // NewRunnableJob constructs a new Runnable task defined as Kubernetes
 func NewRunnableJob(
 	clientset kubernetes.Interface,
 	job *batchv1.Job,
+	opts ...RunnableJobOption,
 ) Runnable {
-	return &runnableJob{
+	r := &runnableJob{
 		clientset:  clientset,
 		logsReader: NewLogsReader(clientset),
 		job:        job,
+		template:   job.DeepCopy(),
+		deleteOptions: metav1.DeleteOptions{
+			PropagationPolicy: ptr.To(metav1.DeletePropagationBackground),
+		},
+		throttle: func(context.Context) error { return nil },
+		clock:    realClock{},
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
 }
 
 // Run runs synchronously the task as Kubernetes job.
-// This method blocks and waits for the job completion or failure.
+// This method blocks and waits for the job completion or failure, or until ctx is done.
 func (r *runnableJob) Run(ctx context.Context) error {
 	var err error
 	r.job, err = r.clientset.BatchV1().Jobs(r.job.Namespace).Create(ctx, r.job, metav1.CreateOptions{})
 	if err != nil {
 		return err
 	}
+	if err = r.created(ctx); err != nil {
+		return err
+	}
+	// informers are stopped once the job is done or ctx is cancelled
+	informersCtx, stopInformers := context.WithCancel(ctx)
 	informerFactory := informers.NewSharedInformerFactoryWithOptions(
 		r.clientset,
 		defaultResyncDuration,
 		informers.WithNamespace(r.job.Namespace),
 	)
-	jobsInformer := informerFactory.Batch().V1().Jobs()
-	complete := make(chan error)
+	defer func() {
+		stopInformers()
+		informerFactory.Shutdown()
+	}()
+	// handlers match the job by name, a recreated job keeps the name of the evicted one
+	jobName := r.job.Name
+	events := make(chan jobEvent)
+	// report a job event to Run, handlers don't block once Run returned
+	report := func(event jobEvent) {
+		select {
+		case events <- event:
+		case <-informersCtx.Done():
+		}
+	}
 
+	jobsInformer := informerFactory.Batch().V1().Jobs()
 	_, err = jobsInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		UpdateFunc: func(_, newObj interface{}) {
 			newJob, ok := newObj.(*batchv1.Job)
 			if !ok {
 				return
 			}
-			if r.job.UID != newJob.UID {
+			if newJob.Name != jobName {
 				return
 			}
 			if len(newJob.Status.Conditions) == 0 {
@@ -66,9 +162,14 @@ func (r *runnableJob) Run(ctx context.Context) error {
 			}
 			switch condition := newJob.Status.Conditions[0]; condition.Type {
 			case batchv1.JobComplete:
-				complete <- nil
+				report(jobEvent{uid: newJob.UID})
 			case batchv1.JobFailed:
-				complete <- fmt.Errorf("job failed: %s: %s", condition.Reason, condition.Message)
+				report(jobEvent{
+					uid:    newJob.UID,
+					err:    fmt.Errorf("%w: %s: %s", ErrJobFailed, condition.Reason, condition.Message),
+					failed: newJob,
+					reason: condition.Reason,
+				})
 			}
 		},
 	})
@@ -79,12 +180,15 @@ func (r *runnableJob) Run(ctx context.Context) error {
 	_, err = eventsInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			event := obj.(*corev1.Event)
-			if event.InvolvedObject.UID != r.job.UID {
+			if event.InvolvedObject.Kind != "Job" || event.InvolvedObject.Name != jobName {
 				return
 			}
 
 			if event.Type == corev1.EventTypeWarning {
-				complete <- fmt.Errorf("warning event received: %s (%s)", event.Message, event.Reason)
+				report(jobEvent{
+					uid: event.InvolvedObject.UID,
+					err: fmt.Errorf("warning event received: %s (%s)", event.Message, event.Reason),
+				})
 				return
 			}
 		},
@@ -92,10 +196,32 @@ func (r *runnableJob) Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	informerFactory.Start(wait.NeverStop)
-	informerFactory.WaitForCacheSync(wait.NeverStop)
+	if r.evictionRetries > 0 {
+		onPod := func(obj interface{}) {
+			pod, ok := obj.(*corev1.Pod)
+			if !ok || !podEvicted(pod) {
+				return
+			}
+			controllerRef := metav1.GetControllerOf(pod)
+			if controllerRef == nil || controllerRef.Kind != "Job" || controllerRef.Name != jobName {
+				return
+			}
+			report(jobEvent{uid: controllerRef.UID, evicted: true})
+		}
+		_, err = informerFactory.Core().V1().Pods().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: onPod,
+			UpdateFunc: func(_, newObj interface{}) {
+				onPod(newObj)
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	informerFactory.Start(informersCtx.Done())
+	informerFactory.WaitForCacheSync(informersCtx.Done())
 
-	err = <-complete
+	err = r.wait(ctx, events)
 
 	if err != nil {
 		r.logTerminatedContainersErrors(ctx)
@@ -104,10 +230,127 @@ func (r *runnableJob) Run(ctx context.Context) error {
 	return err
 }
 
+// jobEvent is a job outcome or a pod eviction reported by the informers
+type jobEvent struct {
+	uid types.UID
+	err error
+	// evicted is set when a job pod was evicted
+	evicted bool
+	// failed is the job that reached the Failed condition with reason
+	failed *batchv1.Job
+	reason string
+}
+
+// wait returns the job outcome, the job is recreated when its pod is evicted until eviction retries are exhausted.
+// Events of an evicted job (e.g. the BackoffLimitExceeded failure following the eviction) are ignored once it is recreated
+func (r *runnableJob) wait(ctx context.Context, events <-chan jobEvent) error {
+	for {
+		var event jobEvent
+		select {
+		case event = <-events:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if event.uid != r.job.UID {
+			continue
+		}
+		if r.evictions < r.evictionRetries && (event.evicted || (event.err != nil && r.hasEvictedPod(ctx))) {
+			r.evictions++
+			slog.Info(fmt.Sprintf("Job %q pod was evicted, recreating job (retry %d/%d)", r.job.Namespace+"/"+r.job.Name, r.evictions, r.evictionRetries))
+			if err := r.recreate(ctx); err != nil {
+				return fmt.Errorf("recreating evicted job: %w", err)
+			}
+			continue
+		}
+		if event.evicted {
+			// retries are exhausted, the job is left to its backoffLimit
+			continue
+		}
+		if event.failed != nil && r.onFailed != nil {
+			r.onFailed(event.failed, event.reason)
+		}
+		return event.err
+	}
+}
+
+// hasEvictedPod returns whether a pod of the job was evicted
+func (r *runnableJob) hasEvictedPod(ctx context.Context) bool {
+	selector, err := getJobPodsSelector(ctx, r.clientset, r.job)
+	if err != nil {
+		return false
+	}
+	pods, err := r.clientset.CoreV1().Pods(r.job.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return false
+	}
+	for i := range pods.Items {
+		if podEvicted(&pods.Items[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// recreate delete the job with its pods and create it again, creation is retried until the deleted job
+// is gone or ctx is done
+func (r *runnableJob) recreate(ctx context.Context) error {
+	if err := r.throttle(ctx); err != nil {
+		return err
+	}
+	err := r.clientset.BatchV1().Jobs(r.job.Namespace).Delete(ctx, r.job.Name, r.deleteOptions)
+	if err != nil && !k8sapierror.IsNotFound(err) {
+		return err
+	}
+	job := r.template.DeepCopy()
+	for {
+		if err := r.throttle(ctx); err != nil {
+			return err
+		}
+		created, err := r.clientset.BatchV1().Jobs(job.Namespace).Create(ctx, job, metav1.CreateOptions{})
+		if err == nil {
+			r.job = created
+			return r.created(ctx)
+		}
+		if !k8sapierror.IsAlreadyExists(err) {
+			return err
+		}
+		select {
+		case <-r.clock.After(defaultRecreatePollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// created call the onCreated hook (if any) with the job
+func (r *runnableJob) created(ctx context.Context) error {
+	if r.onCreated == nil {
+		return nil
+	}
+	return r.onCreated(ctx, r.job)
+}
+
+// podEvicted returns whether the pod failed because it was evicted or disrupted
+func podEvicted(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodFailed {
+		return false
+	}
+	if pod.Status.Reason == "Evicted" {
+		return true
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.DisruptionTarget && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *runnableJob) logTerminatedContainersErrors(ctx context.Context) {
-	statuses, err := r.logsReader.GetTerminatedContainersStatusesByJob(ctx, r.job)
+	job := r.job
+	statuses, err := r.logsReader.GetTerminatedContainersStatusesByJob(ctx, job)
 	if err != nil {
-		slog.Error(fmt.Sprintf("Error while getting terminated containers statuses for job %q", r.job.Namespace+"/"+r.job.Name))
+		slog.Error(fmt.Sprintf("Error while getting terminated containers statuses for job %q", job.Namespace+"/"+job.Name))
 	}
 
 	for _, status := range statuses {
@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	k8sapierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+)
+
+func TestRunnableJobRecreate(t *testing.T) {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "node-collector-1", Namespace: "trivy-temp"}}
+	clientset := fake.NewSimpleClientset(job)
+	// the deleted job is gone after the first create retry
+	var creates int
+	clientset.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		creates++
+		if creates == 1 {
+			return true, nil, k8sapierror.NewAlreadyExists(batchv1.Resource("jobs"), job.Name)
+		}
+		return false, nil, nil
+	})
+	var throttled int
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	r := NewRunnableJob(clientset, job,
+		WithDeleteOptions(metav1.DeleteOptions{GracePeriodSeconds: ptr.To[int64](0)}),
+		WithThrottle(func(context.Context) error {
+			throttled++
+			return nil
+		}),
+		WithRunnableJobClock(clock),
+	).(*runnableJob)
+
+	done := make(chan error)
+	go func() {
+		done <- r.recreate(context.Background())
+	}()
+	assert.Eventually(t, clock.hasWaiters, time.Second, time.Millisecond)
+	clock.Advance(defaultRecreatePollInterval)
+	assert.NoError(t, <-done)
+	assert.Equal(t, 2, creates)
+	// delete and both creates are throttled
+	assert.Equal(t, 3, throttled)
+	for _, action := range clientset.Actions() {
+		if deleteAction, ok := action.(k8stesting.DeleteAction); ok {
+			assert.Equal(t, ptr.To[int64](0), deleteAction.GetDeleteOptions().GracePeriodSeconds)
+		}
+	}
+
+	// recreate stops once ctx is done
+	creates = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		done <- r.recreate(ctx)
+	}()
+	assert.Eventually(t, clock.hasWaiters, time.Second, time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
@@ -35,8 +35,9 @@ type Runner interface {
 // New constructs a new ready-to-use Runner for running a Runnable task.
 func New(opts ...RunnerOption) Runner {
 	r := &runner{
-		complete:        make(chan error),
+		complete:        make(chan error, 1),
 		timeoutDuration: 0,
+		clock:           realClock{},
 	}
 	for _, opt := range opts {
 		opt(r)
@@ -52,35 +53,50 @@ func WithTimeout(timeout time.Duration) RunnerOption {
 	}
 }
 
+func WithClock(clock Clock) RunnerOption {
+	return func(j *runner) {
+		j.clock = clock
+	}
+}
+
 type runner struct {
-	// complete channel reports that processing is done
+	// complete channel reports that processing is done, it is buffered so the task
+	// does not block once Run returned on timeout or ctx cancellation
 	complete chan error
 	// timeout duration
 	timeoutDuration time.Duration
+	clock           Clock
 }
 
 // Run runs the specified task and monitors channel events.
+// The task context is cancelled once Run returns (e.g. on timeout).
 func (r *runner) Run(ctx context.Context, task Runnable) error {
+	taskCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	go func() {
-		r.complete <- task.Run(ctx)
+		r.complete <- task.Run(taskCtx)
 	}()
 	if r.timeoutDuration > 0 {
 		return r.runWithTimeout(ctx)
 	}
-	return r.runAndWaitForever()
+	return r.runAndWaitForever(ctx)
 
 }
 
-func (r *runner) runAndWaitForever() error {
-	return <-r.complete
+func (r *runner) runAndWaitForever(ctx context.Context) error {
+	select {
+	case err := <-r.complete:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (r *runner) runWithTimeout(ctx context.Context) error {
 	// context timeout also can be set on caller side
+	var timeout <-chan time.Time
 	if _, ok := ctx.Deadline(); !ok {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, r.timeoutDuration)
-		defer cancel()
+		timeout = r.clock.After(r.timeoutDuration)
 	}
 	select {
 	// Signaled when processing is done.
@@ -89,5 +105,7 @@ func (r *runner) runWithTimeout(ctx context.Context) error {
 	// Signaled when we run out of time.
 	case <-ctx.Done():
 		return ErrTimeout
+	case <-timeout:
+		return ErrTimeout
 	}
 }
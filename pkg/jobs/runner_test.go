@@ -0,0 +1,130 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeClockWaiter
+}
+
+type fakeClockWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, fakeClockWaiter{deadline: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance move the clock forward, firing waiters whose deadline passed
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	var waiters []fakeClockWaiter
+	for _, w := range c.waiters {
+		if w.deadline.After(c.now) {
+			waiters = append(waiters, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = waiters
+}
+
+func (c *fakeClock) hasWaiters() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters) > 0
+}
+
+func TestRunnerTimeoutWithClock(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	block := make(chan struct{})
+	defer close(block)
+	task := RunnableFunc(func(ctx context.Context) error {
+		<-block
+		return nil
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- New(WithTimeout(time.Hour), WithClock(clock)).Run(context.Background(), task)
+	}()
+	assert.Eventually(t, clock.hasWaiters, time.Second, time.Millisecond)
+
+	clock.Advance(59 * time.Minute)
+	select {
+	case err := <-done:
+		t.Fatalf("runner returned before deadline: %v", err)
+	default:
+	}
+
+	clock.Advance(time.Minute)
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, ErrTimeout)
+	case <-time.After(5 * time.Second):
+		t.Fatal("runner did not time out")
+	}
+}
+
+func TestRunnerCompleteBeforeTimeout(t *testing.T) {
+	clock := &fakeClock{}
+	err := New(WithTimeout(time.Hour), WithClock(clock)).Run(context.Background(), RunnableFunc(func(ctx context.Context) error {
+		return nil
+	}))
+	assert.NoError(t, err)
+}
+
+func TestRunnerContextCancelledWithoutTimeout(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := New().Run(ctx, RunnableFunc(func(ctx context.Context) error {
+		<-block
+		return nil
+	}))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRunnerTimeoutCancelTask(t *testing.T) {
+	clock := &fakeClock{}
+	taskCtx := make(chan context.Context, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- New(WithTimeout(time.Hour), WithClock(clock)).Run(context.Background(), RunnableFunc(func(ctx context.Context) error {
+			taskCtx <- ctx
+			<-ctx.Done()
+			return ctx.Err()
+		}))
+	}()
+	assert.Eventually(t, clock.hasWaiters, time.Second, time.Millisecond)
+	clock.Advance(time.Hour)
+	assert.ErrorIs(t, <-done, ErrTimeout)
+	// task is cancelled once timed out
+	select {
+	case <-(<-taskCtx).Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("task was not cancelled")
+	}
+}
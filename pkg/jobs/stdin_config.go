@@ -0,0 +1,109 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sapierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// stdinConfigMountPath and stdinConfigKey locate the config payload in the collector container
+	stdinConfigMountPath = "/etc/node-collector"
+	stdinConfigKey       = "config"
+)
+
+// WithStdinConfig pass a config payload to the collector, it is written to an ephemeral ConfigMap
+// mounted read-only at /etc/node-collector/config, passed with --config. The ConfigMap is owned by the job
+// so it is garbage collected with it, and deleted once the collection is done
+func WithStdinConfig(config []byte) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.stdinConfig = config
+	}
+}
+
+// stdinConfigMapName returns the name of the job config payload ConfigMap
+func stdinConfigMapName(job *batchv1.Job) string {
+	return job.Name + "-config"
+}
+
+// mountStdinConfig add the config payload ConfigMap volume to the job collector container
+// and point the collector to it
+func mountStdinConfig(job *batchv1.Job) {
+	podSpec := &job.Spec.Template.Spec
+	volumeName := uniqueVolumeName(podSpec, "stdin-config")
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: volumeName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: stdinConfigMapName(job)},
+			},
+		},
+	})
+	podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+		Name:      volumeName,
+		MountPath: stdinConfigMountPath,
+		ReadOnly:  true,
+	})
+	podSpec.Containers[0].Args = append(podSpec.Containers[0].Args, "--config", path.Join(stdinConfigMountPath, stdinConfigKey))
+}
+
+// createStdinConfig create the job config payload ConfigMap, it is created before the job
+// so the owner reference is set once the job is created, see ownStdinConfig
+func (jb *jobCollector) createStdinConfig(ctx context.Context, job *batchv1.Job, ownerReferences []metav1.OwnerReference) error {
+	if err := jb.throttle(ctx); err != nil {
+		return err
+	}
+	_, err := jb.clientset.CoreV1().ConfigMaps(job.Namespace).Create(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            stdinConfigMapName(job),
+			Namespace:       job.Namespace,
+			Labels:          job.Labels,
+			OwnerReferences: ownerReferences,
+		},
+		BinaryData: map[string][]byte{stdinConfigKey: jb.stdinConfig},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("creating config payload configmap: %w", err)
+	}
+	return nil
+}
+
+// ownStdinConfig set the created job as owner of its config payload ConfigMap, so it is not left behind
+// when the collection is interrupted. The ConfigMap of an evicted job may be garbage collected with it, it is then created again
+func (jb *jobCollector) ownStdinConfig(ctx context.Context, job *batchv1.Job) error {
+	ownerReferences := []metav1.OwnerReference{{
+		APIVersion: batchv1.SchemeGroupVersion.String(),
+		Kind:       "Job",
+		Name:       job.Name,
+		UID:        job.UID,
+	}}
+	patch, err := json.Marshal(map[string]any{"metadata": map[string]any{"ownerReferences": ownerReferences}})
+	if err != nil {
+		return err
+	}
+	if err = jb.throttle(ctx); err != nil {
+		return err
+	}
+	_, err = jb.clientset.CoreV1().ConfigMaps(job.Namespace).Patch(ctx, stdinConfigMapName(job), types.MergePatchType, patch, metav1.PatchOptions{})
+	if k8sapierror.IsNotFound(err) {
+		return jb.createStdinConfig(ctx, job, ownerReferences)
+	}
+	if err != nil {
+		return fmt.Errorf("setting config payload configmap owner: %w", err)
+	}
+	return nil
+}
+
+// deleteStdinConfig delete the job config payload ConfigMap, even when ctx is cancelled
+func (jb *jobCollector) deleteStdinConfig(ctx context.Context, job *batchv1.Job) {
+	ctx = context.WithoutCancel(ctx)
+	_ = jb.throttle(ctx)
+	_ = jb.clientset.CoreV1().ConfigMaps(job.Namespace).Delete(ctx, stdinConfigMapName(job), jb.deleteOptions())
+}
@@ -0,0 +1,92 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sapierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestApplyAndCollectStdinConfig(t *testing.T) {
+	config := []byte(`{"checks":["kubelet"]}`)
+	jc, clientset := newTestCollector(nil, WithStdinConfig(config))
+	jc.logsReader = &fakeLogsReader{logs: io.NopCloser(bytes.NewBufferString(`{"info":{}}`))}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+	// record the configmaps while the job runs
+	configMaps := make(chan *corev1.ConfigMapList, 1)
+	clientset.PrependWatchReactor("jobs", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		go func() {
+			configMapList, _ := clientset.CoreV1().ConfigMaps(action.GetNamespace()).List(context.Background(), metav1.ListOptions{})
+			configMaps <- configMapList
+		}()
+		return false, nil, nil
+	})
+
+	_, err := jc.ApplyAndCollect(context.Background(), "node-1")
+	assert.NoError(t, err)
+
+	var job *batchv1.Job
+	var ownerPatch []byte
+	for _, action := range clientset.Actions() {
+		if createAction, ok := action.(k8stesting.CreateAction); ok && action.GetResource().Resource == "jobs" {
+			job = createAction.GetObject().(*batchv1.Job)
+		}
+		if patchAction, ok := action.(k8stesting.PatchAction); ok && action.GetResource().Resource == "configmaps" {
+			ownerPatch = patchAction.GetPatch()
+		}
+	}
+	if !assert.NotNil(t, job) {
+		return
+	}
+	configMapName := job.Name + "-config"
+	// configmap exists while the job runs
+	configMapList := <-configMaps
+	if assert.Len(t, configMapList.Items, 1) {
+		assert.Equal(t, configMapName, configMapList.Items[0].Name)
+		assert.Equal(t, config, configMapList.Items[0].BinaryData["config"])
+	}
+	// configmap is mounted in the collector container
+	podSpec := job.Spec.Template.Spec
+	assert.Contains(t, podSpec.Volumes, corev1.Volume{
+		Name: "stdin-config-0",
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: configMapName},
+			},
+		},
+	})
+	assert.Contains(t, podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+		Name:      "stdin-config-0",
+		MountPath: "/etc/node-collector",
+		ReadOnly:  true,
+	})
+	// collector reads the mounted config
+	assert.Equal(t, []string{"--config", "/etc/node-collector/config"}, podSpec.Containers[0].Args[len(podSpec.Containers[0].Args)-2:])
+	// configmap is owned by the job once created, so it is garbage collected with it
+	assert.JSONEq(t, fmt.Sprintf(`{"metadata":{"ownerReferences":[{"apiVersion":"batch/v1","kind":"Job","name":%q,"uid":""}]}}`, job.Name), string(ownerPatch))
+	// configmap is deleted once done
+	_, err = clientset.CoreV1().ConfigMaps("trivy-temp").Get(context.Background(), configMapName, metav1.GetOptions{})
+	assert.True(t, k8sapierror.IsNotFound(err))
+}
+
+func TestOwnStdinConfigRecreated(t *testing.T) {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "node-collector-1", Namespace: "trivy-temp", UID: "uid-2"}}
+	jc, clientset := newTestCollector(nil, WithStdinConfig([]byte("checks: []")))
+
+	// configmap was garbage collected with the evicted job
+	err := jc.ownStdinConfig(context.Background(), job)
+	assert.NoError(t, err)
+	configMap, err := clientset.CoreV1().ConfigMaps("trivy-temp").Get(context.Background(), "node-collector-1-config", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("checks: []"), configMap.BinaryData["config"])
+	assert.Equal(t, []metav1.OwnerReference{{APIVersion: "batch/v1", Kind: "Job", Name: "node-collector-1", UID: "uid-2"}}, configMap.OwnerReferences)
+}
@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+
+	k8sapierror "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// NodeResult is the collector output of a node
+type NodeResult struct {
+	Node   string
+	Output string
+	Err    error
+}
+
+// WithFailFastOnForbidden abort CollectStream on the first Forbidden error (e.g. the collector
+// is not allowed to create jobs), remaining nodes result in that error rather than being collected
+func WithFailFastOnForbidden(failFast bool) CollectorOption {
+	return func(jc *jobCollector) {
+		jc.failFastOnForbidden = failFast
+	}
+}
+
+// CollectStream collect nodes with up to concurrency collector jobs at a time, results are sent
+// as they finish (in any order) and the channel is closed once all nodes are done.
+// Once ctx is cancelled no new collection is started, remaining nodes result in ctx error
+// (or the Forbidden error with WithFailFastOnForbidden)
+func (jb *jobCollector) CollectStream(ctx context.Context, nodeNames []string, concurrency int) <-chan NodeResult {
+	jb = jb.snapshot()
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	// buffered so collections do not block on a slow (or gone) reader
+	results := make(chan NodeResult, len(nodeNames))
+	go func() {
+		defer cancel(nil)
+		defer close(results)
+		sem := make(chan struct{}, concurrency)
+		var wg sync.WaitGroup
+		for _, nodeName := range nodeNames {
+			if ctx.Err() != nil {
+				results <- NodeResult{Node: nodeName, Err: context.Cause(ctx)}
+				continue
+			}
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				results <- NodeResult{Node: nodeName, Err: context.Cause(ctx)}
+				continue
+			}
+			wg.Add(1)
+			go func(nodeName string) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				output, err := jb.ApplyAndCollect(ctx, nodeName)
+				if jb.failFastOnForbidden && k8sapierror.IsForbidden(err) {
+					// every node would fail the same way, abort the batch
+					cancel(err)
+				}
+				results <- NodeResult{Node: nodeName, Output: output, Err: err}
+			}(nodeName)
+		}
+		wg.Wait()
+	}()
+	return results
+}
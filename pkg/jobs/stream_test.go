@@ -0,0 +1,91 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sapierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// nodeLogsReader return the job node name as logs
+type nodeLogsReader struct {
+	LogsReader
+}
+
+func (r *nodeLogsReader) GetLogsByJobAndContainerName(_ context.Context, job *batchv1.Job, _ string) (io.ReadCloser, error) {
+	nodeName := job.Spec.Template.Spec.NodeSelector[corev1.LabelHostname]
+	return io.NopCloser(bytes.NewBufferString(fmt.Sprintf(`{"node":%q}`, nodeName))), nil
+}
+
+func TestCollectStream(t *testing.T) {
+	jc, clientset := newTestCollector(nil)
+	jc.logsReader = &nodeLogsReader{}
+	completeJobsOnWatch(clientset, batchv1.JobComplete)
+
+	nodeNames := []string{"node-1", "node-2", "node-3", "node-4", "node-5"}
+	got := make(map[string]string)
+	for result := range jc.CollectStream(context.Background(), nodeNames, 2) {
+		assert.NoError(t, result.Err)
+		got[result.Node] = result.Output
+	}
+	// results order is not assumed
+	assert.Len(t, got, len(nodeNames))
+	for _, nodeName := range nodeNames {
+		assert.Equal(t, fmt.Sprintf(`{"node":%q}`, nodeName), got[nodeName])
+	}
+}
+
+func TestCollectStreamCancelled(t *testing.T) {
+	jc, _ := newTestCollector(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var results []NodeResult
+	for result := range jc.CollectStream(ctx, []string{"node-1", "node-2"}, 1) {
+		results = append(results, result)
+	}
+	// no collection is started
+	assert.ElementsMatch(t, []NodeResult{
+		{Node: "node-1", Err: context.Canceled},
+		{Node: "node-2", Err: context.Canceled},
+	}, results)
+}
+
+func TestCollectStreamFailFastOnForbidden(t *testing.T) {
+	nodeNames := []string{"node-1", "node-2", "node-3", "node-4"}
+	var nodes []runtime.Object
+	for _, nodeName := range nodeNames {
+		nodes = append(nodes, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}})
+	}
+	jc, clientset := newTestCollector(nodes, WithFailFastOnForbidden(true))
+	var creates atomic.Int32
+	clientset.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		creates.Add(1)
+		return true, nil, k8sapierror.NewForbidden(batchv1.Resource("jobs"), "", errors.New("not allowed"))
+	})
+
+	var results []NodeResult
+	stream := jc.CollectStream(context.Background(), nodeNames, 1)
+	// options appended while collecting don't apply to the running batch
+	jc.AppendLabels(WithFailFastOnForbidden(false))
+	for result := range stream {
+		results = append(results, result)
+	}
+	// batch stops after the first node
+	assert.Equal(t, int32(1), creates.Load())
+	assert.Len(t, results, len(nodeNames))
+	for _, result := range results {
+		assert.True(t, k8sapierror.IsForbidden(result.Err), result.Err)
+	}
+}
@@ -0,0 +1,46 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	containerimage "github.com/google/go-containerregistry/pkg/name"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+)
+
+// ErrIncompatibleNodeCollector is returned when the node-collector image version output schema is not supported
+var ErrIncompatibleNodeCollector = errors.New("incompatible node-collector version")
+
+// ErrUnknownNodeCollectorVersion is returned when the node-collector image version can't be read from its tag
+// (e.g. latest or a digest reference), so its compatibility can't be checked
+var ErrUnknownNodeCollectorVersion = errors.New("unknown node-collector version")
+
+var (
+	// minNodeCollectorVersion and maxNodeCollectorVersion (exclusive) bound the node-collector
+	// versions which output schema is supported
+	minNodeCollectorVersion = utilversion.MustParseSemantic("0.1.0")
+	maxNodeCollectorVersion = utilversion.MustParseSemantic("1.0.0")
+)
+
+// checkNodeCollectorVersion check the node-collector image tag version is supported. The version is only
+// read from the image tag, the collector is not run to report it: images without a semantic version tag
+// (e.g. latest or digest) fail with ErrUnknownNodeCollectorVersion
+func checkNodeCollectorVersion(image string) error {
+	ref, err := containerimage.ParseReference(image)
+	if err != nil {
+		return fmt.Errorf("parsing node-collector image %q: %w", image, err)
+	}
+	tag, ok := ref.(containerimage.Tag)
+	if !ok {
+		return fmt.Errorf("%w: image %q has no version tag", ErrUnknownNodeCollectorVersion, image)
+	}
+	version, err := utilversion.ParseSemantic(strings.TrimPrefix(tag.TagStr(), "v"))
+	if err != nil {
+		return fmt.Errorf("%w: image %q tag is not a semantic version", ErrUnknownNodeCollectorVersion, image)
+	}
+	if version.LessThan(minNodeCollectorVersion) || !version.LessThan(maxNodeCollectorVersion) {
+		return fmt.Errorf("%w: image %q, supported versions: >= %s, < %s", ErrIncompatibleNodeCollector, image, minNodeCollectorVersion, maxNodeCollectorVersion)
+	}
+	return nil
+}
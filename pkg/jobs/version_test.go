@@ -0,0 +1,33 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckNodeCollectorVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		image   string
+		wantErr error
+	}{
+		{name: "supported version", image: "ghcr.io/aquasecurity/node-collector:0.1.1"},
+		{name: "v prefixed version", image: "ghcr.io/aquasecurity/node-collector:v0.3.0"},
+		{name: "too old", image: "ghcr.io/aquasecurity/node-collector:0.0.5", wantErr: ErrIncompatibleNodeCollector},
+		{name: "too new", image: "ghcr.io/aquasecurity/node-collector:1.0.0", wantErr: ErrIncompatibleNodeCollector},
+		{name: "unversioned tag", image: "ghcr.io/aquasecurity/node-collector:latest", wantErr: ErrUnknownNodeCollectorVersion},
+		{name: "no tag", image: "ghcr.io/aquasecurity/node-collector", wantErr: ErrUnknownNodeCollectorVersion},
+		{name: "digest", image: "ghcr.io/aquasecurity/node-collector@sha256:0000000000000000000000000000000000000000000000000000000000000000", wantErr: ErrUnknownNodeCollectorVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkNodeCollectorVersion(tt.image)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -17,12 +17,15 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/pager"
 	"k8s.io/utils/strings/slices"
 )
 
@@ -101,6 +104,8 @@ type Cluster interface {
 	GetDynamicClient() dynamic.Interface
 	// GetK8sClientSet returns a k8s client set
 	GetK8sClientSet() *kubernetes.Clientset
+	// GetRestConfig returns the k8s client rest config
+	GetRestConfig() *rest.Config
 	// GetGVRs returns cluster GroupVersionResource to query kubernetes, receives
 	// a boolean to determine if returns namespaced GVRs only or all GVRs, unless
 	// resources is passed to filter
@@ -123,6 +128,7 @@ type cluster struct {
 	dynamicClient    dynamic.Interface
 	restMapper       meta.RESTMapper
 	clientset        *kubernetes.Clientset
+	restConfig       *rest.Config
 	cConfig          clientcmd.ClientConfig
 }
 
@@ -240,6 +246,7 @@ func getCluster(clientConfig clientcmd.ClientConfig, kubeConfig *rest.Config, re
 		dynamicClient:    k8sDynamicClient,
 		restMapper:       restMapper,
 		clientset:        kubeClientset,
+		restConfig:       kubeConfig,
 		cConfig:          clientConfig,
 		serverVersion:    serverVersion,
 	}, nil
@@ -270,6 +277,11 @@ func (c *cluster) GetK8sClientSet() *kubernetes.Clientset {
 	return c.clientset
 }
 
+// GetRestConfig returns k8s client rest config
+func (c *cluster) GetRestConfig() *rest.Config {
+	return c.restConfig
+}
+
 // GetGVRs returns cluster GroupVersionResource to query kubernetes, receives
 // a boolean to determine if returns namespaced GVRs only or all GVRs, unless
 // resources is passed to filter
@@ -396,13 +408,50 @@ func GetContainer(imageRef containerimage.Reference, imageName containerimage.Re
 	}, nil
 }
 
+// DefaultNodesPageSize is the number of nodes fetched per list call
+const DefaultNodesPageSize int64 = 500
+
+// ListNodes list cluster nodes page by page, so huge clusters are not fetched in a single call,
+// a pageSize lower or equal to 0 use DefaultNodesPageSize, a non nil fieldSelector
+// (e.g. spec.unschedulable=false) filter nodes server-side
+func ListNodes(ctx context.Context, clientset kubernetes.Interface, pageSize int64, fieldSelector fields.Selector) ([]v1.Node, error) {
+	return listNodes(ctx, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+		return clientset.CoreV1().Nodes().List(ctx, opts)
+	}, pageSize, fieldSelector)
+}
+
+func listNodes(ctx context.Context, listFunc pager.ListPageFunc, pageSize int64, fieldSelector fields.Selector) ([]v1.Node, error) {
+	if pageSize <= 0 {
+		pageSize = DefaultNodesPageSize
+	}
+	listPager := pager.New(listFunc)
+	listPager.PageSize = pageSize
+	nodes := make([]v1.Node, 0)
+	listOptions := metav1.ListOptions{}
+	if fieldSelector != nil {
+		listOptions.FieldSelector = fieldSelector.String()
+	}
+	err := listPager.EachListItem(ctx, listOptions, func(obj runtime.Object) error {
+		node, ok := obj.(*v1.Node)
+		if !ok {
+			return fmt.Errorf("unexpected list item type %T", obj)
+		}
+		nodes = append(nodes, *node)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
 func (c *cluster) CollectNodes(components []bom.Component) ([]bom.NodeInfo, error) {
-	nodes, err := c.clientset.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	nodes, err := ListNodes(context.Background(), c.clientset, DefaultNodesPageSize, nil)
 	if err != nil {
 		return []bom.NodeInfo{}, err
 	}
 	nodesInfo := make([]bom.NodeInfo, 0)
-	for _, node := range nodes.Items {
+	for _, node := range nodes {
 		nf := NodeInfo(node)
 		images := make([]string, 0)
 		for _, image := range node.Status.Images {
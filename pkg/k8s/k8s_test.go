@@ -1,6 +1,9 @@
 package k8s
 
 import (
+	"context"
+	"fmt"
+	"strconv"
 	"testing"
 
 	"github.com/aquasecurity/trivy-kubernetes/pkg/bom"
@@ -9,7 +12,11 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
@@ -39,6 +46,7 @@ func TestGetCurrentNamespace(t *testing.T) {
 			cluster, err := getCluster(fakeConfig, fakeKubeconfig, nil, "", true)
 			assert.NoError(t, err)
 			assert.Equal(t, test.ExpectedNamespace, cluster.GetCurrentNamespace())
+			assert.Equal(t, fakeKubeconfig, cluster.GetRestConfig())
 		})
 	}
 }
@@ -224,3 +232,56 @@ func TestNodeInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestListNodes(t *testing.T) {
+	var nodes []v1.Node
+	for i := 0; i < 5; i++ {
+		nodes = append(nodes, v1.Node{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("node-%d", i)}})
+	}
+	var listOptions []metav1.ListOptions
+	// serve pages by continue token
+	listFunc := func(_ context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+		listOptions = append(listOptions, opts)
+		start := 0
+		if opts.Continue != "" {
+			start, _ = strconv.Atoi(opts.Continue)
+		}
+		end := min(start+int(opts.Limit), len(nodes))
+		nodeList := &v1.NodeList{Items: nodes[start:end]}
+		if end < len(nodes) {
+			nodeList.Continue = strconv.Itoa(end)
+		}
+		return nodeList, nil
+	}
+
+	gotNodes, err := listNodes(context.Background(), listFunc, 2, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, nodes, gotNodes)
+	assert.Equal(t, []metav1.ListOptions{
+		{Limit: 2},
+		{Limit: 2, Continue: "2"},
+		{Limit: 2, Continue: "4"},
+	}, listOptions)
+}
+
+func TestListNodesClientset(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}},
+	)
+	gotNodes, err := ListNodes(context.Background(), clientset, 0, nil)
+	assert.NoError(t, err)
+	assert.Len(t, gotNodes, 2)
+}
+
+func TestListNodesFieldSelector(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	_, err := ListNodes(context.Background(), clientset, 0, fields.OneTermEqualSelector("spec.unschedulable", "false"))
+	assert.NoError(t, err)
+	actions := clientset.Actions()
+	if assert.Len(t, actions, 1) {
+		listAction, ok := actions[0].(k8stesting.ListAction)
+		assert.True(t, ok)
+		assert.Equal(t, "spec.unschedulable=false", listAction.GetListRestrictions().Fields.String())
+	}
+}